@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"errors"
 	"log/slog"
 	"os"
@@ -8,6 +9,8 @@ import (
 	"github.com/urfave/cli/v2"
 
 	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/plugins"
 )
 
 var log *slog.Logger
@@ -34,10 +37,9 @@ var flagHTTPAPIEndpoint = &cli.StringFlag{
 }
 
 var flagQueue = &cli.StringFlag{
-	Name:     "queue",
-	Aliases:  []string{"q"},
-	Usage:    "Name of the source queue to manage.",
-	Required: true,
+	Name:    "queue",
+	Aliases: []string{"q"},
+	Usage:   "Name of the source queue to manage. Required by all commands except resume.",
 }
 
 var flagTempQueue = &cli.StringFlag{
@@ -53,6 +55,65 @@ var flagFilter = &cli.StringFlag{
 	Usage:   "Filter messages based on filter expression (https://expr-lang.org/).",
 }
 
+var flagConfirmWindow = &cli.IntFlag{
+	Name:  "confirm-window",
+	Usage: "Maximum number of published messages waiting for publisher confirmation. Source messages are acknowledged only after their publishings are confirmed. Values > 1 increase throughput.",
+	Value: 1,
+}
+
+var flagIdleTimeout = &cli.DurationFlag{
+	Name:  "idle-timeout",
+	Usage: "Safety net for detecting the end of the queue. Each phase ends once all messages present in the queue at its start are processed, or if no message arrives for the idle timeout (reported as an error). Defaults to 30s if the number of messages is known, 1s otherwise.",
+}
+
+var flagJournalDir = &cli.StringFlag{
+	Name:    "journal-dir",
+	Usage:   "Directory where operation journals are stored. Journals are used to resume failed operations (see resume command).",
+	EnvVars: []string{"RABBITMQ_MESSAGE_OPS_JOURNAL_DIR"},
+	Value:   journal.DefaultDir(),
+}
+
+var flagForce = &cli.BoolFlag{
+	Name:  "force",
+	Usage: "Run the operation even if other consumers or producers use the source, temporary or destination queue.",
+}
+
+var flagExclusive = &cli.BoolFlag{
+	Name:  "exclusive",
+	Usage: "Consume the source and temporary queue exclusively, so that no other consumer can attach during the operation. Not supported for streams.",
+}
+
+var flagQuiesce = &cli.BoolFlag{
+	Name:  "quiesce",
+	Usage: "Hold messages published to the source queue during the operation by moving its bindings to a holding queue. Afterwards, the bindings are restored and the held messages are appended to the source queue. Not supported for streams.",
+}
+
+var flagSpool = &cli.BoolFlag{
+	Name:  "spool",
+	Usage: "Keep messages in a local spool file in the journal directory instead of a temporary queue. The spool is synced to disk before source messages are acknowledged and is read sequentially, so it can hold queues larger than memory. Not supported for streams.",
+}
+
+var flagUnordered = &cli.BoolFlag{
+	Name:  "unordered",
+	Usage: "Process the source queue in a single pass without the temporary queue. The order of messages is NOT preserved. Only supported by purge, move and copy commands, not supported for streams. Removed messages are not archived, so purge and move require no-archive flag.",
+}
+
+var flagNoArchive = &cli.BoolFlag{
+	Name:  "no-archive",
+	Usage: "Do not archive messages removed by purge, move, split, browse, exec, plugin and webhook commands. By default, removed messages are archived in the journal directory, so that the operation can be undone with undo command.",
+}
+
+var flagYes = &cli.BoolFlag{
+	Name:    "yes",
+	Aliases: []string{"y"},
+	Usage:   "Run the operation without showing the impact preview and asking for confirmation.",
+}
+
+var flagPlugin = &cli.StringFlag{
+	Name:  "plugin",
+	Usage: "WebAssembly plugin (.wasm) selecting messages in addition to the filter and/or handling them with plugin command. Plugins run sandboxed, without filesystem and network access.",
+}
+
 var flagVerbosity = &cli.StringFlag{
 	Name:    "verbosity",
 	Aliases: []string{"v"},
@@ -63,12 +124,24 @@ func buildCLIApp() *cli.App {
 	return &cli.App{
 		Name:  "rabbitmq-cli",
 		Usage: "Manage rabbitmq queues",
+		// prompts share the buffered reader, so that input buffered by one prompt is not lost for the next one
+		Reader: bufio.NewReader(os.Stdin),
 		Flags: []cli.Flag{
 			flagEndpoint,
 			flagHTTPAPIEndpoint,
 			flagQueue,
 			flagTempQueue,
 			flagFilter,
+			flagConfirmWindow,
+			flagIdleTimeout,
+			flagJournalDir,
+			flagForce,
+			flagExclusive,
+			flagQuiesce,
+			flagSpool,
+			flagUnordered,
+			flagNoArchive,
+			flagPlugin,
 			flagVerbosity,
 		},
 		Before: func(ctx *cli.Context) error {
@@ -91,15 +164,28 @@ func buildCLIApp() *cli.App {
 			}
 			util.AttachClient(ctx, client)
 
-			publisher, err := buildPublisher(endpoint)
+			publisher, err := buildPublisher(endpoint, ctx.Int("confirm-window"))
 			if err != nil {
 				return err
 			}
 			util.AttachPublisher(ctx, publisher)
 
+			if path := ctx.String("plugin"); path != "" {
+				plugin, err := plugins.Load(ctx.Context, path)
+				if err != nil {
+					return err
+				}
+				util.AttachPlugin(ctx, plugin)
+			}
+
 			return nil
 		},
 		After: func(ctx *cli.Context) error {
+			if plugin := util.GetPlugin(ctx); plugin != nil {
+				if closeErr := plugin.Close(ctx.Context); closeErr != nil {
+					log.Error("error while closing plugin", slog.Any("error", closeErr))
+				}
+			}
 			publisher := util.GetPublisher(ctx)
 			if publisher == nil {
 				return nil
@@ -112,9 +198,18 @@ func buildCLIApp() *cli.App {
 		},
 		Commands: []*cli.Command{
 			viewMessages(),
+			browseMessages(),
 			moveMessages(),
 			copyMessages(),
+			splitMessages(),
 			purgeMessages(),
+			editMessages(),
+			execMessages(),
+			pluginMessages(),
+			webhookMessages(),
+			resumeOperation(),
+			recoverQueues(),
+			undoOperation(),
 		},
 	}
 }
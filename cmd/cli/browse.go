@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/browser"
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+)
+
+func browseMessages() *cli.Command {
+	return &cli.Command{
+		Name:  "browse",
+		Usage: "Browse messages in a terminal UI and apply marked actions",
+		Description: `Collects messages from the source queue in a view pass and shows them in a full-screen browser.
+Messages can be searched, filtered with filter expressions, inspected and marked for move, copy or purge.
+Marked actions are applied in a single pass preserving the original order of the queue once the browser is closed with "q".
+The --filter flag sets the initial filter of the browser.`,
+		UsageText: `rabbitmq-cli browse [command options]
+Example: rabbitmq-cli -q <srcQueueName> -f 'type == "<some.msg.type>"' browse -d <destQueueName>`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "destination",
+				Aliases: []string{"d"},
+				Usage:   "Name of the destination queue of messages marked for move or copy.",
+			},
+			&cli.IntFlag{
+				Name:  "limit",
+				Usage: "Maximum number of messages at the front of the queue loaded into the browser.",
+				Value: 10000,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			srcQueue := c.String("queue")
+			if srcQueue == "" {
+				return errors.New(`required flag "queue" not set`)
+			}
+			if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+				return errors.New("browse command requires a terminal")
+			}
+			for _, flag := range []string{"unordered", "temp-queue"} {
+				if c.IsSet(flag) {
+					return fmt.Errorf("browse command does not support %q flag", flag)
+				}
+			}
+			destQueue := c.String("destination")
+			if destQueue != "" {
+				// check if destination queue exists
+				if _, err := util.GetClient(c).GetQueueInfo(destQueue); err != nil {
+					return err
+				}
+			}
+
+			// all messages are collected, so that their index is their position in the queue
+			collector := handlers.NewCollectHandler(c.Int("limit"))
+			viewOp := journal.Operation{Command: "view", SrcQueue: srcQueue}
+			if err := runOperation(c, viewOp, nil, collector); err != nil {
+				return err
+			}
+
+			b, err := browser.New(srcQueue, collector.Messages(), destQueue).WithFilter(c.String("filter"))
+			if err != nil {
+				return err
+			}
+			marks, apply, err := b.Run(os.Stdin, os.Stdout)
+			if err != nil {
+				return err
+			}
+			if !apply {
+				_, _ = fmt.Fprintln(c.App.Writer, "No actions applied.")
+				return nil
+			}
+
+			markHandler := handlers.NewMarkHandler(browserMarks(c, collector.Messages(), marks, destQueue))
+			applyOp := journal.Operation{Command: "browse", Args: map[string]string{"destination": destQueue}, SrcQueue: srcQueue}
+			if err = runOperation(c, applyOp, nil, markHandler); err != nil {
+				return err
+			}
+
+			_, _ = fmt.Fprintf(c.App.Writer, "Applied %v of %v marked actions.\n", markHandler.Applied(), len(marks))
+			for _, mark := range markHandler.Pending() {
+				_, _ = fmt.Fprintf(c.App.Writer, "Message #%v (message ID %q) was not found at its position, the action was not applied.\n", mark.Position+1, mark.Identity.MessageID)
+			}
+			return nil
+		},
+	}
+}
+
+// region Helpers
+
+// browserMarks builds the handlers of the actions marked in the browser.
+func browserMarks(c *cli.Context, messages []amqp091.Delivery, marks map[int]browser.Action, destQueue string) []handlers.Mark {
+	actionHandlers := map[browser.Action]handlers.MessageHandler{
+		browser.ActionMove:  handlers.NewMoveHandler(util.GetPublisher(c), destQueue),
+		browser.ActionCopy:  handlers.NewCopyHandler(util.GetPublisher(c), destQueue),
+		browser.ActionPurge: handlers.NewPurgeHandler(),
+	}
+	result := make([]handlers.Mark, 0, len(marks))
+	for position, action := range marks {
+		result = append(result, handlers.Mark{
+			Position: position,
+			Identity: journal.IdentityFromDelivery(messages[position]),
+			Handler:  actionHandlers[action],
+		})
+	}
+	return result
+}
+
+// endregion
@@ -0,0 +1,490 @@
+package browser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rabbitmq/amqp091-go"
+	"golang.org/x/term"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
+)
+
+const (
+	ActionMove  Action = "move"
+	ActionCopy  Action = "copy"
+	ActionPurge Action = "purge"
+)
+
+const (
+	keyUp = iota + 256
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyEscape
+	keyBackspace
+	keyInterrupt
+)
+
+// errInputClosed is returned by readKey once the input is closed. The browser is then quit without applying the marks.
+var errInputClosed = errors.New("browser: input closed")
+
+const helpLine = "↑/↓ move  enter details  / search  n next  f filter  m move  c copy  p purge  u unmark  q apply and quit  x quit"
+
+type Action string
+
+// Browser is a full-screen terminal browser of collected queue messages, which lets the user mark messages for move, copy or purge.
+// Messages are identified by their position in the queue, i.e. their index in the collected messages.
+type Browser struct {
+	queue       string
+	messages    []amqp091.Delivery
+	destination string
+
+	in     *bufio.Reader
+	out    *bufio.Writer
+	width  int
+	height int
+
+	visible []int // positions of the messages matching the filter
+	filter  string
+	search  string
+	cursor  int // index in visible
+	offset  int // first visible row
+	detail  bool
+	scroll  int // first line of the details
+	status  string
+	marks   map[int]Action
+}
+
+func New(queue string, messages []amqp091.Delivery, destination string) *Browser {
+	b := &Browser{queue: queue, messages: messages, destination: destination, marks: make(map[int]Action)}
+	b.visible = make([]int, len(messages))
+	for i := range messages {
+		b.visible[i] = i
+	}
+	return b
+}
+
+// region Public
+
+// WithFilter sets the initial filter expression.
+func (b *Browser) WithFilter(filter string) (*Browser, error) {
+	if filter == "" {
+		return b, nil
+	}
+	return b, b.applyFilter(filter)
+}
+
+// Run shows the browser until the user quits. apply is true if the user quit with applying the marks, which are returned by position.
+// Closed input aborts the browser without applying the marks.
+func (b *Browser) Run(in, out *os.File) (marks map[int]Action, apply bool, err error) {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, false, fmt.Errorf("browser: failed to switch terminal to raw mode: %w", err)
+	}
+	defer func() { _ = term.Restore(int(in.Fd()), state) }()
+
+	b.in = bufio.NewReader(in)
+	b.out = bufio.NewWriter(out)
+	// alternate screen keeps the original terminal content, which is restored on exit
+	b.write("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		b.write("\x1b[?25h\x1b[?1049l")
+		_ = b.out.Flush()
+	}()
+
+	for {
+		b.width, b.height, err = term.GetSize(int(out.Fd()))
+		if err != nil {
+			return nil, false, fmt.Errorf("browser: failed to get terminal size: %w", err)
+		}
+		if err = b.render(); err != nil {
+			return nil, false, err
+		}
+		key, err := b.readKey()
+		if err != nil {
+			return nil, false, inputErr(err)
+		}
+		done, apply, err := b.handleKey(key)
+		if err != nil {
+			return nil, false, inputErr(err)
+		}
+		if done {
+			return b.marks, apply, nil
+		}
+	}
+}
+
+// endregion
+
+// region Private
+
+// handleKey handles the pressed key. done is true once the user quits.
+func (b *Browser) handleKey(key rune) (done, apply bool, err error) {
+	b.status = ""
+	if b.detail {
+		return false, false, b.handleDetailKey(key)
+	}
+
+	switch key {
+	case keyUp, 'k':
+		b.moveCursor(-1)
+	case keyDown, 'j':
+		b.moveCursor(1)
+	case keyPageUp:
+		b.moveCursor(-b.listHeight())
+	case keyPageDown, ' ':
+		b.moveCursor(b.listHeight())
+	case keyHome, 'g':
+		b.moveCursor(-len(b.visible))
+	case keyEnd, 'G':
+		b.moveCursor(len(b.visible))
+	case keyEnter:
+		if len(b.visible) > 0 {
+			b.detail, b.scroll = true, 0
+		}
+	case '/':
+		search, ok, err := b.readLine("search: ", b.search)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		b.search = search
+		b.findNext(0)
+	case 'n':
+		b.findNext(1)
+	case 'f':
+		filter, ok, err := b.readLine("filter: ", b.filter)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		if err = b.applyFilter(filter); err != nil {
+			b.status = "invalid filter: " + err.Error()
+		}
+	case 'm':
+		b.mark(ActionMove)
+	case 'c':
+		b.mark(ActionCopy)
+	case 'p':
+		b.mark(ActionPurge)
+	case 'u':
+		if position, ok := b.current(); ok {
+			delete(b.marks, position)
+			b.moveCursor(1)
+		}
+	case 'q':
+		if len(b.marks) == 0 {
+			return true, false, nil
+		}
+		confirmed, err := b.confirm(fmt.Sprintf("Apply %v marked actions to %v? [y/N] ", len(b.marks), b.queue))
+		return confirmed, confirmed, err
+	case 'x', keyInterrupt:
+		if len(b.marks) == 0 {
+			return true, false, nil
+		}
+		confirmed, err := b.confirm(fmt.Sprintf("Quit without applying %v marked actions? [y/N] ", len(b.marks)))
+		return confirmed, false, err
+	}
+	return false, false, nil
+}
+
+func (b *Browser) handleDetailKey(key rune) error {
+	switch key {
+	case keyUp, 'k':
+		b.scroll = max(b.scroll-1, 0)
+	case keyDown, 'j':
+		b.scroll++
+	case keyPageUp:
+		b.scroll = max(b.scroll-b.listHeight(), 0)
+	case keyPageDown, ' ':
+		b.scroll += b.listHeight()
+	case keyEnter, keyEscape, 'q':
+		b.detail = false
+	case 'm':
+		b.mark(ActionMove)
+	case 'c':
+		b.mark(ActionCopy)
+	case 'p':
+		b.mark(ActionPurge)
+	case 'u':
+		if position, ok := b.current(); ok {
+			delete(b.marks, position)
+		}
+	}
+	return nil
+}
+
+func (b *Browser) mark(action Action) {
+	position, ok := b.current()
+	if !ok {
+		return
+	}
+	if action != ActionPurge && b.destination == "" {
+		b.status = fmt.Sprintf("cannot mark messages for %v without destination queue, please run browse with --destination", action)
+		return
+	}
+	b.marks[position] = action
+	if !b.detail {
+		b.moveCursor(1)
+	}
+}
+
+func (b *Browser) applyFilter(filter string) error {
+	visible := make([]int, 0, len(b.messages))
+	if filter == "" {
+		for i := range b.messages {
+			visible = append(visible, i)
+		}
+	} else {
+		selector, err := selectors.NewFilterExprSelector(filter)
+		if err != nil {
+			return err
+		}
+		for i, msg := range b.messages {
+			selected, err := selector.IsSelected(msg)
+			if err != nil {
+				return err
+			}
+			if selected {
+				visible = append(visible, i)
+			}
+		}
+	}
+
+	// keep the cursor on the same message if it is still visible
+	current, _ := b.current()
+	b.filter, b.visible, b.cursor, b.offset = filter, visible, 0, 0
+	for i, position := range visible {
+		if position >= current {
+			b.cursor = i
+			break
+		}
+	}
+	return nil
+}
+
+// findNext moves the cursor to the next visible message which contains the search text, starting skip messages after the cursor.
+func (b *Browser) findNext(skip int) {
+	if b.search == "" || len(b.visible) == 0 {
+		return
+	}
+	for i := 0; i < len(b.visible); i++ {
+		index := (b.cursor + skip + i) % len(b.visible)
+		data, err := mappers.PrettyDelivery(b.messages[b.visible[index]])
+		if err == nil && strings.Contains(string(data), b.search) {
+			b.cursor = index
+			return
+		}
+	}
+	b.status = fmt.Sprintf("%q not found", b.search)
+}
+
+func (b *Browser) moveCursor(delta int) {
+	b.cursor = min(max(b.cursor+delta, 0), max(len(b.visible)-1, 0))
+}
+
+func (b *Browser) current() (int, bool) {
+	if len(b.visible) == 0 {
+		return 0, false
+	}
+	return b.visible[b.cursor], true
+}
+
+// listHeight is the number of message rows, without the header, status and help lines.
+func (b *Browser) listHeight() int {
+	return max(b.height-4, 1)
+}
+
+// endregion
+
+// region Rendering
+
+func (b *Browser) render() error {
+	b.write("\x1b[H\x1b[2J")
+	b.line(fmt.Sprintf("Queue: %v  messages: %v  shown: %v  marked: %v  filter: %v", b.queue, len(b.messages), len(b.visible), len(b.marks), b.filter), true)
+	b.line("", false)
+
+	if b.detail {
+		if err := b.renderDetail(); err != nil {
+			return err
+		}
+	} else {
+		b.renderList()
+	}
+
+	b.write(fmt.Sprintf("\x1b[%v;1H", b.height-1))
+	b.line(b.status, false)
+	b.line(helpLine, true)
+	return b.out.Flush()
+}
+
+func (b *Browser) renderList() {
+	rows := b.listHeight() - 1
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+rows {
+		b.offset = b.cursor - rows + 1
+	}
+	for i := b.offset; i < len(b.visible) && i < b.offset+rows; i++ {
+		position := b.visible[i]
+		msg := b.messages[position]
+		row := fmt.Sprintf("%-6v %-7v %-36v %-24v %v", position+1, markLabel(b.marks[position]), msg.MessageId, msg.Type, strconv.Quote(string(msg.Body)))
+		b.line(row, i == b.cursor)
+	}
+	if len(b.visible) == 0 {
+		b.line("No messages.", false)
+	}
+}
+
+func (b *Browser) renderDetail() error {
+	position, _ := b.current()
+	data, err := mappers.PrettyDelivery(b.messages[position])
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(fmt.Sprintf("Message #%v %v\n%s", position+1, markLabel(b.marks[position]), data), "\n")
+	rows := b.listHeight() - 1
+	b.scroll = min(b.scroll, max(len(lines)-rows, 0))
+	for i := b.scroll; i < len(lines) && i < b.scroll+rows; i++ {
+		b.line(lines[i], false)
+	}
+	return nil
+}
+
+// line writes the text as a single terminal line, truncated to the terminal width.
+func (b *Browser) line(text string, highlight bool) {
+	if utf8.RuneCountInString(text) > b.width {
+		text = string([]rune(text)[:max(b.width-1, 0)]) + "…"
+	}
+	if highlight {
+		text = "\x1b[7m" + text + strings.Repeat(" ", max(b.width-utf8.RuneCountInString(text), 0)) + "\x1b[0m"
+	}
+	b.write(text + "\x1b[K\r\n")
+}
+
+func (b *Browser) write(text string) {
+	_, _ = b.out.WriteString(text)
+}
+
+func markLabel(action Action) string {
+	if action == "" {
+		return ""
+	}
+	return "[" + string(action) + "]"
+}
+
+// endregion
+
+// region Input
+
+// readLine reads a line of text in the status line. ok is false if the user cancelled it with escape.
+func (b *Browser) readLine(prompt, value string) (string, bool, error) {
+	for {
+		b.write(fmt.Sprintf("\x1b[%v;1H", b.height-1))
+		b.line(prompt+value, false)
+		if err := b.out.Flush(); err != nil {
+			return "", false, err
+		}
+
+		key, err := b.readKey()
+		if err != nil {
+			return "", false, err
+		}
+		switch {
+		case key == keyEnter:
+			return value, true, nil
+		case key == keyEscape || key == keyInterrupt:
+			return "", false, nil
+		case key == keyBackspace:
+			if runes := []rune(value); len(runes) > 0 {
+				value = string(runes[:len(runes)-1])
+			}
+		case key < keyUp && key >= ' ':
+			value += string(key)
+		}
+	}
+}
+
+func (b *Browser) confirm(prompt string) (bool, error) {
+	answer, ok, err := b.readLine(prompt, "")
+	if err != nil || !ok {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// readKey reads a single key press, decoding the escape sequences of special keys.
+func (b *Browser) readKey() (rune, error) {
+	r, _, err := b.in.ReadRune()
+	if errors.Is(err, io.EOF) {
+		// nothing can be confirmed once the input is closed, so the browser is aborted
+		return 0, errInputClosed
+	}
+	if err != nil {
+		return 0, err
+	}
+	switch r {
+	case '\r', '\n':
+		return keyEnter, nil
+	case 127, '\b':
+		return keyBackspace, nil
+	case 3:
+		return keyInterrupt, nil
+	case 27:
+	default:
+		return r, nil
+	}
+
+	// escape sequence arrives at once, a lone escape key does not continue
+	if b.in.Buffered() == 0 {
+		return keyEscape, nil
+	}
+	if next, _ := b.in.ReadByte(); next != '[' && next != 'O' {
+		return keyEscape, nil
+	}
+	var sequence []byte
+	for b.in.Buffered() > 0 {
+		c, _ := b.in.ReadByte()
+		sequence = append(sequence, c)
+		if c >= 'A' && c <= 'Z' || c == '~' {
+			break
+		}
+	}
+	switch string(sequence) {
+	case "A":
+		return keyUp, nil
+	case "B":
+		return keyDown, nil
+	case "5~":
+		return keyPageUp, nil
+	case "6~":
+		return keyPageDown, nil
+	case "H", "1~":
+		return keyHome, nil
+	case "F", "4~":
+		return keyEnd, nil
+	default:
+		return keyEscape, nil
+	}
+}
+
+// inputErr returns nil if the input was closed, which aborts the browser without applying the marks.
+func inputErr(err error) error {
+	if errors.Is(err, errInputClosed) {
+		return nil
+	}
+	return err
+}
+
+// endregion
@@ -0,0 +1,239 @@
+package browser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/browser"
+)
+
+func TestBrowser(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Browser")
+}
+
+var _ = Describe("Browser", func() {
+	var messages []amqp091.Delivery
+
+	BeforeEach(func() {
+		messages = []amqp091.Delivery{
+			{MessageId: "msg-1", Type: "a"},
+			{MessageId: "msg-2", Type: "b"},
+			{MessageId: "msg-3", Type: "a"},
+			{MessageId: "msg-4", Type: "b"},
+		}
+	})
+
+	// press handles the keys and returns the result of the last one
+	press := func(b *browser.Browser, keys ...rune) (done, apply bool) {
+		var err error
+		for _, key := range keys {
+			done, apply, err = b.HandleKey(key)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		return done, apply
+	}
+
+	Context("filter", func() {
+		It("shows only messages matching the initial filter", func() {
+			b, err := browser.New("queue", messages, "").WithFilter(`type == "b"`)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(b.Visible()).To(Equal([]int{1, 3}))
+			Expect(b.Current()).To(Equal(1))
+		})
+
+		It("returns error if the initial filter is invalid", func() {
+			_, err := browser.New("queue", messages, "").WithFilter(`type ==`)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("keeps the cursor on the next matching message when the filter changes", func() {
+			b := browser.New("queue", messages, "").WithInput(`type == "a"` + "\r")
+			press(b, browser.KeyDown)
+			Expect(b.Current()).To(Equal(1))
+
+			press(b, 'f')
+			Expect(b.Visible()).To(Equal([]int{0, 2}))
+			Expect(b.Current()).To(Equal(2))
+		})
+
+		It("shows all messages once the filter is cleared", func() {
+			b, err := browser.New("queue", messages, "").WithFilter(`type == "a"`)
+			Expect(err).ToNot(HaveOccurred())
+			b.WithInput(strings.Repeat("\x7f", len(`type == "a"`)) + "\r")
+
+			press(b, 'f')
+			Expect(b.Visible()).To(Equal([]int{0, 1, 2, 3}))
+		})
+
+		It("keeps the previous filter if the new one is invalid", func() {
+			b, err := browser.New("queue", messages, "").WithFilter(`type == "a"`)
+			Expect(err).ToNot(HaveOccurred())
+			b.WithInput(" &&\r")
+
+			press(b, 'f')
+			Expect(b.Visible()).To(Equal([]int{0, 2}))
+			Expect(b.Status()).To(HavePrefix("invalid filter"))
+		})
+
+		It("keeps the previous filter if editing is cancelled", func() {
+			b, err := browser.New("queue", messages, "").WithFilter(`type == "a"`)
+			Expect(err).ToNot(HaveOccurred())
+			b.WithInput("\x7f\x7f\x1b")
+
+			press(b, 'f')
+			Expect(b.Visible()).To(Equal([]int{0, 2}))
+		})
+	})
+
+	Context("marks", func() {
+		It("marks messages by their position and moves to the next one", func() {
+			b := browser.New("queue", messages, "destination").WithInput("")
+
+			press(b, 'm', 'c', 'p')
+			Expect(b.Marks()).To(Equal(map[int]browser.Action{0: browser.ActionMove, 1: browser.ActionCopy, 2: browser.ActionPurge}))
+			Expect(b.Current()).To(Equal(3))
+		})
+
+		It("replaces and removes the mark of a message", func() {
+			b := browser.New("queue", messages, "destination").WithInput("")
+
+			press(b, 'm', 'm', browser.KeyUp, 'p')
+			Expect(b.Marks()).To(Equal(map[int]browser.Action{0: browser.ActionMove, 1: browser.ActionPurge}))
+
+			press(b, browser.KeyHome, 'u')
+			Expect(b.Marks()).To(Equal(map[int]browser.Action{1: browser.ActionPurge}))
+		})
+
+		It("marks messages of the filtered view by their position in the queue", func() {
+			b, err := browser.New("queue", messages, "destination").WithFilter(`type == "b"`)
+			Expect(err).ToNot(HaveOccurred())
+			b.WithInput("")
+
+			press(b, browser.KeyDown, 'p')
+			Expect(b.Marks()).To(Equal(map[int]browser.Action{3: browser.ActionPurge}))
+		})
+
+		It("marks the shown message in the details without moving", func() {
+			b := browser.New("queue", messages, "destination").WithInput("")
+
+			press(b, browser.KeyDown, browser.KeyEnter, 'c', 'j')
+			Expect(b.Marks()).To(Equal(map[int]browser.Action{1: browser.ActionCopy}))
+			Expect(b.Current()).To(Equal(1))
+
+			press(b, 'q', 'm')
+			Expect(b.Marks()).To(Equal(map[int]browser.Action{1: browser.ActionMove}))
+			Expect(b.Current()).To(Equal(2))
+		})
+
+		It("does not mark messages for move or copy without destination", func() {
+			b := browser.New("queue", messages, "").WithInput("")
+
+			press(b, 'm', 'c')
+			Expect(b.Marks()).To(BeEmpty())
+			Expect(b.Status()).To(ContainSubstring("without destination queue"))
+
+			press(b, 'p')
+			Expect(b.Marks()).To(Equal(map[int]browser.Action{0: browser.ActionPurge}))
+		})
+	})
+
+	Context("quitting", func() {
+		It("quits without applying if nothing is marked", func() {
+			b := browser.New("queue", messages, "").WithInput("")
+
+			done, apply := press(b, 'q')
+			Expect(done).To(BeTrue())
+			Expect(apply).To(BeFalse())
+		})
+
+		It("applies the marks once confirmed", func() {
+			b := browser.New("queue", messages, "").WithInput("y\r")
+
+			done, apply := press(b, 'p', 'q')
+			Expect(done).To(BeTrue())
+			Expect(apply).To(BeTrue())
+			Expect(b.Marks()).To(Equal(map[int]browser.Action{0: browser.ActionPurge}))
+		})
+
+		It("continues browsing if applying is not confirmed", func() {
+			b := browser.New("queue", messages, "").WithInput("n\r")
+
+			done, apply := press(b, 'p', 'q')
+			Expect(done).To(BeFalse())
+			Expect(apply).To(BeFalse())
+			Expect(b.Marks()).To(HaveLen(1))
+		})
+
+		It("quits without applying the marks once confirmed", func() {
+			b := browser.New("queue", messages, "").WithInput("yes\r")
+
+			done, apply := press(b, 'p', 'x')
+			Expect(done).To(BeTrue())
+			Expect(apply).To(BeFalse())
+		})
+
+		It("asks before quitting with marks on interrupt", func() {
+			b := browser.New("queue", messages, "").WithInput("\x1b")
+
+			done, apply := press(b, 'p', browser.KeyInterrupt)
+			Expect(done).To(BeFalse())
+			Expect(apply).To(BeFalse())
+		})
+
+		It("returns error if the input is closed while confirming", func() {
+			b := browser.New("queue", messages, "").WithInput("")
+			press(b, 'p')
+
+			_, _, err := b.HandleKey('q')
+			Expect(err).To(MatchError(browser.ErrInputClosed))
+		})
+	})
+
+	Context("key decoding", func() {
+		It("decodes special keys and escape sequences", func() {
+			keys := map[string]rune{
+				"a":       'a',
+				"\r":      browser.KeyEnter,
+				"\n":      browser.KeyEnter,
+				"\x7f":    browser.KeyBackspace,
+				"\b":      browser.KeyBackspace,
+				"\x03":    browser.KeyInterrupt,
+				"\x1b":    browser.KeyEscape,
+				"\x1b[A":  browser.KeyUp,
+				"\x1b[B":  browser.KeyDown,
+				"\x1bOA":  browser.KeyUp,
+				"\x1b[5~": browser.KeyPageUp,
+				"\x1b[6~": browser.KeyPageDown,
+				"\x1b[H":  browser.KeyHome,
+				"\x1b[1~": browser.KeyHome,
+				"\x1b[F":  browser.KeyEnd,
+				"\x1b[4~": browser.KeyEnd,
+				"\x1b[C":  browser.KeyEscape,
+				"\x1bx":   browser.KeyEscape,
+			}
+			for input, expected := range keys {
+				key, err := browser.New("queue", messages, "").WithInput(input).ReadKey()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(key).To(Equal(expected), "input %q", input)
+			}
+		})
+
+		It("reads keys one after another", func() {
+			b := browser.New("queue", messages, "").WithInput("\x1b[Bj")
+
+			Expect(b.ReadKey()).To(Equal(browser.KeyDown))
+			Expect(b.ReadKey()).To(Equal('j'))
+		})
+
+		It("returns error once the input is closed", func() {
+			_, err := browser.New("queue", messages, "").WithInput("").ReadKey()
+			Expect(err).To(MatchError(browser.ErrInputClosed))
+		})
+	})
+})
@@ -0,0 +1,56 @@
+package browser
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+const (
+	KeyUp        rune = keyUp
+	KeyDown      rune = keyDown
+	KeyPageUp    rune = keyPageUp
+	KeyPageDown  rune = keyPageDown
+	KeyHome      rune = keyHome
+	KeyEnd       rune = keyEnd
+	KeyEnter     rune = keyEnter
+	KeyEscape    rune = keyEscape
+	KeyBackspace rune = keyBackspace
+	KeyInterrupt rune = keyInterrupt
+)
+
+var ErrInputClosed = errInputClosed
+
+// WithInput replaces the terminal with the input and a discarded output of the default terminal size.
+func (b *Browser) WithInput(input string) *Browser {
+	b.in = bufio.NewReader(strings.NewReader(input))
+	b.out = bufio.NewWriter(io.Discard)
+	b.width, b.height = 80, 24
+	return b
+}
+
+func (b *Browser) HandleKey(key rune) (done, apply bool, err error) {
+	return b.handleKey(key)
+}
+
+func (b *Browser) ReadKey() (rune, error) {
+	return b.readKey()
+}
+
+func (b *Browser) Marks() map[int]Action {
+	return b.marks
+}
+
+func (b *Browser) Visible() []int {
+	return b.visible
+}
+
+// Current returns the position of the message under the cursor.
+func (b *Browser) Current() int {
+	position, _ := b.current()
+	return position
+}
+
+func (b *Browser) Status() string {
+	return b.status
+}
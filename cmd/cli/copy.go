@@ -1,34 +1,143 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+
 	"github.com/urfave/cli/v2"
 
 	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
 )
 
+// replayDefaultTimestampHeader is the header set by the message timestamp plugin of RabbitMQ, with the time in milliseconds.
+const replayDefaultTimestampHeader = "timestamp_in_ms"
+
 func copyMessages() *cli.Command {
 	return &cli.Command{
 		Name:  "copy",
-		Usage: "Copy messages from source to destination queue",
+		Usage: "Copy messages from source to destination queues",
+		Description: `Copies each selected message to all destination queues and to the queue computed by the destination expression.
+A source message is handled only once all its copies are confirmed. The number of confirmed copies per destination queue is printed at the end.
+If copying a message to one of the destinations fails, its copies in the other destinations are kept, so copying it again (e.g. on resume) duplicates them.
+With --replay, messages from a stream are copied with their original gaps, based on their timestamp header in milliseconds (see "replay-timestamp-header" flag)
+or on their timestamp property, which has a resolution of one second.`,
 		UsageText: `rabbitmq-cli copy [command options]
-Example: rabbitmq-cli -q <srcQueueName> -f 'type == "<some.msg.type>"' copy -d <destQueueName>`,
+Example: rabbitmq-cli -q <srcQueueName> -f 'type == "<some.msg.type>"' copy -d <destQueueName1> -d <destQueueName2>
+Example: rabbitmq-cli -q <srcQueueName> copy --destination-expr '"debug." + headers.tenant'
+Example: rabbitmq-cli -q <srcStreamName> copy -d <destQueueName> --replay --replay-speed 10 --replay-max-gap 5s`,
 		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:    "destination",
+				Aliases: []string{"d"},
+				Usage:   "Name of the destination queue to copy messages to, can be repeated.",
+			},
+			&cli.StringFlag{
+				Name:  "destination-expr",
+				Usage: "Expression computing the destination queue of each message (https://expr-lang.org/), e.g. '\"debug.\" + headers.tenant'. Computed queues must exist.",
+			},
+			flagRate,
+			flagBurst,
+			flagMaxDestinationDepth,
+			&cli.BoolFlag{
+				Name:  "replay",
+				Usage: "Copy messages from a stream with the original gaps between their timestamps (timestamp property). Only supported for streams.",
+			},
+			&cli.Float64Flag{
+				Name:  "replay-speed",
+				Usage: "Speed multiplier of the replay, e.g. 10 replays ten times faster than the original.",
+				Value: 1,
+			},
+			&cli.DurationFlag{
+				Name:  "replay-max-gap",
+				Usage: "Maximum gap between two replayed messages (after applying the speed). Not limited by default.",
+			},
 			&cli.StringFlag{
-				Name:     "destination",
-				Aliases:  []string{"d"},
-				Usage:    "Name of the destination queue to copy messages to.",
-				Required: true,
+				Name:  "replay-timestamp-header",
+				Usage: "Header with the time of the message in milliseconds since the epoch, used instead of the timestamp property, which only has a resolution of one second. Empty disables it.",
+				Value: replayDefaultTimestampHeader,
+			},
+			&cli.BoolFlag{
+				Name:    "interactive",
+				Aliases: []string{"i"},
+				Usage:   "Not supported by copy, the command fails if it is set. Waiting for a decision on each message would break the original gaps of a replay.",
 			},
 		},
 		Action: func(c *cli.Context) error {
-			destQueue := c.String("destination")
-			// check if destination queue exists
-			_, err := util.GetClient(c).GetQueueInfo(destQueue)
+			if c.Bool("interactive") {
+				return errors.New(`interactive review is not supported by copy command, since waiting for a decision on each message would break the original gaps between replayed messages (see "replay" flag). Please select the copied messages with a filter instead`)
+			}
+			destQueues := c.StringSlice("destination")
+			// check if destination queues exist
+			for _, destQueue := range destQueues {
+				if _, err := util.GetClient(c).GetQueueInfo(destQueue); err != nil {
+					return err
+				}
+			}
+			handler, err := copyHandler(c, commandArgs(c))
 			if err != nil {
 				return err
 			}
-			return manageQueue(c, handlers.NewCopyHandler(util.GetPublisher(c), destQueue))
+			if err = manageQueue(c, handler); err != nil {
+				return err
+			}
+			printCounts(c, "Copied", handler.Counts())
+			return nil
 		},
 	}
 }
+
+// region Helpers
+
+// copyHandler builds the handler copying messages to the destination queues and to the queue computed by the destination expression
+// from the (journaled) command arguments.
+func copyHandler(c *cli.Context, args map[string]string) (*handlers.CopyHandler, error) {
+	destQueues, err := argValues(args, "destination")
+	if err != nil {
+		return nil, err
+	}
+	destinationExpr := args["destination-expr"]
+	if len(destQueues) == 0 && destinationExpr == "" {
+		return nil, errors.New(`copy command requires "destination" or "destination-expr" flag`)
+	}
+	for i, destQueue := range destQueues {
+		if slices.Contains(destQueues[i+1:], destQueue) {
+			return nil, fmt.Errorf("destination queue %v is provided more than once", destQueue)
+		}
+	}
+	publisher, err := destinationPublisher(c, args)
+	if err != nil {
+		return nil, err
+	}
+	handler := handlers.NewCopyHandler(publisher, destQueues...)
+	if destinationExpr == "" {
+		return handler, nil
+	}
+
+	keyExpr, err := selectors.NewKeyExpr(destinationExpr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination expression: %w", err)
+	}
+	return handler.
+		WithDestinationExpr(keyExpr).
+		WithQueueCheck(func(queue string) error {
+			_, err := util.GetClient(c).GetQueueInfo(queue)
+			return err
+		}), nil
+}
+
+// printCounts prints the number of messages published to each destination queue.
+func printCounts(c *cli.Context, action string, counts map[string]int) {
+	destQueues := make([]string, 0, len(counts))
+	for destQueue := range counts {
+		destQueues = append(destQueues, destQueue)
+	}
+	slices.Sort(destQueues)
+	for _, destQueue := range destQueues {
+		_, _ = fmt.Fprintf(c.App.Writer, "%v %v messages to %v.\n", action, counts[destQueue], destQueue)
+	}
+}
+
+// endregion
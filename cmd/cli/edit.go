@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+)
+
+func editMessages() *cli.Command {
+	return &cli.Command{
+		Name:  "edit",
+		Usage: "Edit messages in the queue",
+		Description: `Opens each selected message as a JSON document (properties and body) in the editor from VISUAL or EDITOR environment variable.
+Edited messages are kept in the source queue at their original positions, unchanged documents keep the original messages.
+If the editor fails or the edited document is invalid, the error is shown and the document can be edited again, the original message kept, or the operation aborted.`,
+		UsageText: `rabbitmq-cli edit [command options]
+Example: EDITOR=nano rabbitmq-cli -q <srcQueueName> -f 'messageID == "<messageID>"' edit`,
+		Action: func(c *cli.Context) error {
+			return manageQueue(c, handlers.NewEditHandler(messageEditor(c)))
+		},
+	}
+}
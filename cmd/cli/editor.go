@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
+)
+
+// defaultEditor is used if neither VISUAL nor EDITOR environment variable is set.
+const defaultEditor = "vi"
+
+// messageEditor returns the editor which opens the message as a JSON document (properties and body) in the user's editor and returns the edited message.
+// If the editor fails or the edited document is invalid, the user decides whether to edit the document again, keep the original message or abort.
+func messageEditor(c *cli.Context) func(msg amqp091.Publishing) (amqp091.Publishing, bool, error) {
+	return func(msg amqp091.Publishing) (amqp091.Publishing, bool, error) {
+		doc, err := mappers.PublishingDocument(msg)
+		if err != nil {
+			return amqp091.Publishing{}, false, err
+		}
+
+		file, err := os.CreateTemp("", "rabbitmq-message-ops-*.json")
+		if err != nil {
+			return amqp091.Publishing{}, false, err
+		}
+		defer func() { _ = os.Remove(file.Name()) }()
+		_, err = file.Write(append(doc, '\n'))
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return amqp091.Publishing{}, false, err
+		}
+
+		for {
+			edited, changed, err := editDocument(file.Name(), doc, msg)
+			if err == nil {
+				return edited, changed, nil
+			}
+			// the file keeps the edited document, so that editing it again continues from the user's changes
+			_, _ = fmt.Fprintf(c.App.ErrWriter, "ERROR: %v\n", err)
+			answer, promptErr := prompt(c, "Edit the document again, keep the original message or abort? [e/k/A]: ")
+			if promptErr != nil {
+				return amqp091.Publishing{}, false, errors.Join(err, promptErr)
+			}
+			switch strings.ToLower(answer) {
+			case "e", "edit":
+				continue
+			case "k", "keep":
+				return msg, false, nil
+			default:
+				return amqp091.Publishing{}, false, err
+			}
+		}
+	}
+}
+
+// editDocument opens the file in the editor and parses the edited document. Unchanged document keeps the original message.
+func editDocument(path string, doc []byte, msg amqp091.Publishing) (amqp091.Publishing, bool, error) {
+	if err := runEditor(path); err != nil {
+		return amqp091.Publishing{}, false, err
+	}
+	editedDoc, err := os.ReadFile(path)
+	if err != nil {
+		return amqp091.Publishing{}, false, err
+	}
+	if bytes.Equal(bytes.TrimSpace(editedDoc), doc) {
+		// unchanged document keeps the original message, e.g. header types which JSON cannot represent
+		return msg, false, nil
+	}
+	edited, err := mappers.ParsePublishingDocument(editedDoc)
+	if err != nil {
+		return amqp091.Publishing{}, false, fmt.Errorf("invalid document: %w", err)
+	}
+	return edited, true, nil
+}
+
+// runEditor opens the file in the editor from VISUAL or EDITOR environment variable and waits until it is closed.
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = defaultEditor
+	}
+	// editor may contain arguments, e.g. "code --wait"
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		return errors.New("editor is not set")
+	}
+
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %v failed: %w", editor, err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+)
+
+func execMessages() *cli.Command {
+	return &cli.Command{
+		Name:  "exec",
+		Usage: "Handle messages with an external command",
+		Description: `Starts the command once and sends each selected message to its stdin as a single line JSON document (properties and body, like in edit command).
+For each message, the command must write a single line JSON verdict to its stdout:
+  {"verdict":"keep"}                                  keeps the message
+  {"verdict":"drop"}                                  removes the message
+  {"verdict":"move","queue":"<queue>"}                moves the message to the queue
+  {"verdict":"replace","message":{<document>}}        keeps the replacement in place of the message
+Move verdict can also carry a replacement message. Stderr of the command is passed through.
+The command can be given with --command, split on whitespace without interpreting quotes, or as arguments after --, which are passed as they are.
+It runs in its own process group, so the first interrupt lets it finish the current message, while aborting the operation kills it.`,
+		UsageText: `rabbitmq-cli exec [command options] [-- command [arguments...]]
+Example: rabbitmq-cli -q <srcQueueName> -f 'type == "<some.msg.type>"' exec --command "python3 remediate.py"
+Example: rabbitmq-cli -q <srcQueueName> exec -- python3 remediate.py --reason "bad payload"`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "command",
+				Aliases: []string{"c"},
+				Usage:   "Command to run, arguments are separated by whitespace and quotes are not interpreted. Pass the command after -- for arguments containing whitespace.",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			args, err := execArgs(commandArgs(c))
+			if err != nil {
+				return err
+			}
+			handler, err := startExec(c, args)
+			if err != nil {
+				return err
+			}
+			err = manageQueue(c, handler)
+			return errors.Join(err, handler.Close())
+		},
+	}
+}
+
+// region Helpers
+
+// execArgs returns the command and its arguments, given either with the command flag or as arguments, see commandArgs.
+func execArgs(args map[string]string) ([]string, error) {
+	argv, err := argValues(args, "argv")
+	if err != nil {
+		return nil, err
+	}
+	if args["command"] != "" {
+		if len(argv) > 0 {
+			return nil, errors.New("exec command must be given either with --command or as arguments, not both")
+		}
+		argv = strings.Fields(args["command"])
+	}
+	if len(argv) == 0 {
+		return nil, errors.New("exec command is empty")
+	}
+	return argv, nil
+}
+
+// startExec starts the command and returns the handler which exchanges messages and verdicts with it.
+// The process is killed once the context of the operation is cancelled, which also ends a pending read of its verdict.
+func startExec(c *cli.Context, args []string) (*execHandler, error) {
+	cmd := exec.CommandContext(c.Context, args[0], args[1:]...)
+	isolateProcess(cmd)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start exec command: %w", err)
+	}
+	log.Info("exec command started", slog.String("command", strings.Join(args, " ")), slog.Int("pid", cmd.Process.Pid))
+
+	handler := handlers.NewExecHandler(util.GetPublisher(c), stdin, stdout).
+		WithQueueCheck(func(queue string) error {
+			_, err := util.GetClient(c).GetQueueInfo(queue)
+			return err
+		})
+	return &execHandler{ExecHandler: handler, cmd: cmd, stdin: stdin}, nil
+}
+
+// endregion
+
+// region Structs
+
+// execHandler owns the process of the exec handler.
+type execHandler struct {
+	*handlers.ExecHandler
+	cmd   *exec.Cmd
+	stdin io.Closer
+}
+
+// Close closes the stdin of the process, so that it ends, and waits for it.
+func (h *execHandler) Close() error {
+	_ = h.stdin.Close()
+	if err := h.cmd.Wait(); err != nil {
+		return fmt.Errorf("exec command failed: %w", err)
+	}
+	return nil
+}
+
+// endregion
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+)
+
+var flagInteractive = &cli.BoolFlag{
+	Name:    "interactive",
+	Aliases: []string{"i"},
+	Usage:   "Review each selected message and decide whether it is kept, acted on or edited. Quitting keeps the remaining messages in their original order.",
+}
+
+// interactive wraps the handler, so that the user reviews each selected message if interactive mode is set.
+// Quitting stops processing the source queue gracefully, like the first SIGINT.
+func interactive(c *cli.Context, handler handlers.MessageHandler, action string, enabled bool) handlers.MessageHandler {
+	if !enabled {
+		return handler
+	}
+	quit := make(chan struct{})
+	c.Context = util.WithStop(c.Context, mergeStop(util.GetStop(c), quit))
+	return handlers.NewInteractiveHandler(handler, action, c.App.Reader, c.App.Writer).
+		WithEditor(messageEditor(c)).
+		WithQuit(sync.OnceFunc(func() { close(quit) }))
+}
+
+// mergeStop returns a channel which is closed once any of the provided channels is closed.
+func mergeStop(stops ...<-chan struct{}) <-chan struct{} {
+	merged := make(chan struct{})
+	closeMerged := sync.OnceFunc(func() { close(merged) })
+	for _, stop := range stops {
+		if stop == nil {
+			continue
+		}
+		go func(stop <-chan struct{}) {
+			select {
+			case <-stop:
+				closeMerged()
+			case <-merged:
+			}
+		}(stop)
+	}
+	return merged
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
 )
 
 // endregion
@@ -13,19 +16,32 @@ import (
 func main() {
 	// Build CLI app
 	app := buildCLIApp()
-	// Graceful shutdown in case of SIGINT or SIGTERM
+	// The first SIGINT stops the operation gracefully, the second one or SIGTERM aborts it
 	ctx, cancel := context.WithCancel(context.Background())
+	stop := make(chan struct{})
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
+		defer cancel()
+		select {
+		case sig := <-signals:
+			if sig != syscall.SIGINT {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+		close(stop)
+		// show the progress of moving processed messages back to the source queue
+		levelVar.Set(min(levelVar.Level(), slog.LevelInfo))
+		_, _ = fmt.Fprintln(os.Stderr, "Stopping: remaining messages are no longer processed, the source queue is restored in its original order. Press Ctrl+C again to abort immediately.")
 		select {
 		case <-signals:
 		case <-ctx.Done():
 		}
-		cancel()
 	}()
 	// Run CLI app
-	if err := app.RunContext(ctx, os.Args); err != nil {
+	if err := app.RunContext(util.WithStop(ctx, stop), os.Args); err != nil {
 		log.Error("error occurred while running cli app", slog.Any("error", err))
 		os.Exit(1)
 	}
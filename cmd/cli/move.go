@@ -20,6 +20,11 @@ Example: rabbitmq-cli -q <srcQueueName> -f 'type == "<some.msg.type>"' move -d <
 				Usage:    "Name of the destination queue to move messages to.",
 				Required: true,
 			},
+			flagYes,
+			flagInteractive,
+			flagRate,
+			flagBurst,
+			flagMaxDestinationDepth,
 		},
 		Action: func(c *cli.Context) error {
 			destQueue := c.String("destination")
@@ -28,7 +33,12 @@ Example: rabbitmq-cli -q <srcQueueName> -f 'type == "<some.msg.type>"' move -d <
 			if err != nil {
 				return err
 			}
-			return manageQueue(c, handlers.NewMoveHandler(util.GetPublisher(c), destQueue))
+			publisher, err := destinationPublisher(c, commandArgs(c))
+			if err != nil {
+				return err
+			}
+			handler := handlers.NewMoveHandler(publisher, destQueue)
+			return manageQueue(c, interactive(c, handler, "move to "+destQueue, c.Bool("interactive")))
 		},
 	}
 }
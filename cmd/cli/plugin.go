@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/plugins"
+)
+
+func pluginMessages() *cli.Command {
+	return &cli.Command{
+		Name:  "plugin",
+		Usage: "Handle messages with a WebAssembly plugin",
+		Description: `Handles each selected message with the handle function of the plugin provided with --plugin flag.
+The plugin returns the same JSON verdicts as the command of exec command (keep, drop, move or replace).
+If the plugin also exports the select function, only messages selected by both the filter and the plugin are handled.`,
+		UsageText: `rabbitmq-cli plugin
+Example: rabbitmq-cli -q <srcQueueName> --plugin remediate.wasm plugin`,
+		Action: func(c *cli.Context) error {
+			handler, err := pluginHandler(c, util.GetPlugin(c))
+			if err != nil {
+				return err
+			}
+			return manageQueue(c, handler)
+		},
+	}
+}
+
+// region Helpers
+
+// pluginHandler builds the handler which applies the verdicts of the plugin.
+func pluginHandler(c *cli.Context, plugin *plugins.Plugin) (*handlers.VerdictHandler, error) {
+	if plugin == nil {
+		return nil, errors.New(`plugin command requires "plugin" flag`)
+	}
+	if !plugin.Handles() {
+		return nil, errors.New("plugin does not export handle function")
+	}
+	handler := handlers.NewVerdictHandler(util.GetPublisher(c), plugin.Decide).
+		WithQueueCheck(func(queue string) error {
+			_, err := util.GetClient(c).GetQueueInfo(queue)
+			return err
+		})
+	return handler, nil
+}
+
+// endregion
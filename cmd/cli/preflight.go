@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+)
+
+// preflight refuses to run the operation if other clients consume from or publish to the queues used by the operation, unless forced.
+// In quiesce mode, publishing to the source queue is still reported: the publish rate does not tell messages routed by the bindings,
+// which are held, from messages published directly to the queue with the default exchange, which are not.
+// Destination queues computed by the destination expression are only known while processing, so they are not checked.
+func preflight(c *cli.Context, op journal.Operation, queues ...string) error {
+	srcQueue := op.SrcQueue
+	if op.Args["destination-expr"] != "" {
+		log.Warn("destination queues computed by the destination expression are not checked for other consumers or producers",
+			slog.String("destinationExpr", op.Args["destination-expr"]),
+		)
+	}
+
+	var active []string
+	for _, queue := range append([]string{srcQueue}, queues...) {
+		if queue == "" {
+			continue
+		}
+		activity, err := util.GetClient(c).GetQueueActivity(queue)
+		if err != nil {
+			return err
+		}
+		if activity.Active() {
+			var note string
+			if queue == srcQueue && c.Bool("quiesce") && activity.PublishRate > 0 {
+				note = ", messages published with the default exchange are not held by quiesce"
+			}
+			active = append(active, fmt.Sprintf("%v (consumers: %v, publish rate: %.2f/s%v)", queue, activity.Consumers, activity.PublishRate, note))
+		}
+	}
+	if len(active) == 0 {
+		return nil
+	}
+
+	msg := "queues are used by other consumers or producers, the result of the operation is unpredictable: " + strings.Join(active, ", ")
+	if c.Bool("force") {
+		log.Warn(msg, slog.Bool("force", true))
+		return nil
+	}
+	return errors.New(msg + `. Stop the other clients or use "--force" flag to run anyway`)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/preview"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/rabbitmq"
+)
+
+const (
+	// previewSampleSize is the maximal number of messages at the front of the source queue sampled for the impact preview.
+	previewSampleSize = 1000
+	// previewTopTypes is the number of most frequent message types shown in the impact preview.
+	previewTopTypes = 5
+)
+
+// confirmImpact shows the impact preview of the destructive command and asks the user to confirm it by typing the source queue name.
+func confirmImpact(c *cli.Context, op journal.Operation) error {
+	if op.Command != "purge" && op.Command != "move" && op.Command != "split" || c.Bool("yes") {
+		return nil
+	}
+
+	sample, err := confirmSampling(c, op)
+	if err != nil {
+		return err
+	}
+	if sample {
+		p, err := samplePreview(c, op)
+		if err != nil {
+			return err
+		}
+		printPreview(c, op, p)
+	}
+
+	answer, err := prompt(c, fmt.Sprintf("Type the name of the source queue (%v) to confirm: ", op.SrcQueue))
+	if err != nil {
+		return err
+	}
+	if answer != op.SrcQueue {
+		return errors.New("operation cancelled")
+	}
+	return nil
+}
+
+// confirmSampling asks the user whether to sample a quorum queue, since sampling increments the delivery count of the sampled messages.
+func confirmSampling(c *cli.Context, op journal.Operation) (bool, error) {
+	queueInfo, err := util.GetClient(c).GetQueueInfo(op.SrcQueue)
+	if err != nil {
+		return false, err
+	}
+	if queueInfo.Type != amqp091.QueueTypeQuorum {
+		return true, nil
+	}
+	_, _ = fmt.Fprintf(c.App.ErrWriter, "WARNING: the preview samples up to %v messages from the front of the quorum queue %v and requeues them, which increments their delivery count. "+
+		"If the queue has a delivery limit (x-delivery-limit), messages which reach it are dead-lettered or dropped.\n",
+		previewSampleSize, op.SrcQueue)
+	return confirm(c, "Sample messages for the impact preview? [y/N]: ")
+}
+
+// samplePreview selects messages from the front of the source queue without acknowledging them.
+// Sampled messages are requeued to their original positions once the consumer is closed, but they are marked as redelivered
+// and their delivery count is incremented in quorum queues.
+func samplePreview(c *cli.Context, op journal.Operation) (*preview.Preview, error) {
+	selector, err := buildSelector(c, op)
+	if err != nil {
+		return nil, err
+	}
+
+	consumer, err := rabbitmq.NewSimpleConsumer(c.String("endpoint"))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := consumer.Close()
+		if closeErr != nil {
+			log.Error("error while closing consumer", slog.Any("error", closeErr))
+		}
+	}()
+
+	total, err := consumer.MessageCount(op.SrcQueue)
+	if err != nil {
+		return nil, err
+	}
+	p := preview.NewPreview(total)
+	limit := min(total, previewSampleSize)
+	if limit == 0 {
+		return p, nil
+	}
+
+	// all sampled messages are held unacknowledged, so the prefetch must cover the whole sample
+	if err = consumer.Prefetch(limit); err != nil {
+		return nil, err
+	}
+	messages, err := consumer.Consume(op.SrcQueue)
+	if err != nil {
+		return nil, err
+	}
+
+	idleTimeout := c.Duration("idle-timeout")
+	if idleTimeout == 0 {
+		// the sample size is not known up front
+		idleTimeout = time.Second
+	}
+	timer := time.NewTimer(idleTimeout)
+	defer timer.Stop()
+	for p.Sampled < limit {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return nil, consumer.Err()
+			}
+			selected, err := selector.IsSelected(msg)
+			if err != nil {
+				return nil, err
+			}
+			p.Add(msg, selected)
+			timer.Reset(idleTimeout)
+		case <-timer.C:
+			log.Warn("no message arrived within idle timeout, preview is based on the messages sampled so far", slog.Int("sampledMessages", p.Sampled))
+			return p, nil
+		case <-c.Context.Done():
+			return nil, c.Context.Err()
+		}
+	}
+	return p, nil
+}
+
+func printPreview(c *cli.Context, op journal.Operation, p *preview.Preview) {
+	w := c.App.Writer
+	_, _ = fmt.Fprintf(w, "Impact preview (command: %v, source queue: %v, filter: %q)\n", op.Command, op.SrcQueue, op.Filter)
+	if p.Exact() {
+		_, _ = fmt.Fprintf(w, "  Selected messages: %v of %v\n", p.Selected, p.Total)
+	} else {
+		_, _ = fmt.Fprintf(w, "  Selected messages: ~%v of %v (%v of the first %v sampled messages)\n", p.Estimate(), p.Total, p.Selected, p.Sampled)
+	}
+	if types := p.TopTypes(previewTopTypes); len(types) > 0 {
+		counts := make([]string, 0, len(types))
+		for _, t := range types {
+			counts = append(counts, fmt.Sprintf("%v (%v)", t.Type, t.Count))
+		}
+		_, _ = fmt.Fprintf(w, "  Top types: %v\n", strings.Join(counts, ", "))
+	}
+	if ids := p.ExampleIDs(); len(ids) > 0 {
+		_, _ = fmt.Fprintf(w, "  Example message IDs: %v\n", strings.Join(ids, ", "))
+	}
+	destinations, _ := argValues(op.Args, "destination")
+	switch len(destinations) {
+	case 0:
+		_, _ = fmt.Fprintln(w, "  Selected messages are removed.")
+	case 1:
+		_, _ = fmt.Fprintf(w, "  Selected messages are moved to %v.\n", destinations[0])
+	default:
+		_, _ = fmt.Fprintf(w, "  Selected messages are split into %v.\n", strings.Join(destinations, ", "))
+	}
+}
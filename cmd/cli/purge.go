@@ -12,8 +12,12 @@ func purgeMessages() *cli.Command {
 		Usage: "Purge messages from the queue",
 		UsageText: `rabbitmq-cli purge [command options]
 Example: rabbitmq-cli -q <srcQueueName> -f 'type == "<some.msg.type>"' purge`,
+		Flags: []cli.Flag{
+			flagYes,
+			flagInteractive,
+		},
 		Action: func(c *cli.Context) error {
-			return manageQueue(c, handlers.NewPurgeHandler())
+			return manageQueue(c, interactive(c, handlers.NewPurgeHandler(), "purge", c.Bool("interactive")))
 		},
 	}
 }
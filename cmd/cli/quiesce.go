@@ -0,0 +1,154 @@
+package main
+
+import (
+	"log/slog"
+	"reflect"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/managers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/rabbitmq"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
+)
+
+// quiesce moves the bindings of the source queue to a holding queue, so that messages published during the operation are held there.
+// Bindings are recorded in the journal before they are moved, so that they can be restored after a crash.
+func quiesce(c *cli.Context, opJournal *journal.Journal, vhost string) error {
+	op := opJournal.Operation()
+	q := op.Quiesce
+	// resumed operation is already (partially) quiesced
+	if q == nil {
+		bindings, err := util.GetClient(c).ListQueueBindings(vhost, op.SrcQueue)
+		if err != nil {
+			return err
+		}
+		holdingQueue, err := declareHoldingQueue(c.String("endpoint"))
+		if err != nil {
+			return err
+		}
+
+		q = &journal.Quiesce{Vhost: vhost, HoldingQueue: holdingQueue}
+		for _, binding := range bindings {
+			q.Bindings = append(q.Bindings, journal.Binding{Exchange: binding.Source, RoutingKey: binding.RoutingKey, Arguments: binding.Arguments})
+		}
+		if err = opJournal.Update(func(op *journal.Operation) { op.Quiesce = q }); err != nil {
+			return err
+		}
+	}
+
+	if err := moveBindings(util.GetClient(c), q, op.SrcQueue, q.HoldingQueue); err != nil {
+		return err
+	}
+	log.Info("source queue traffic is held",
+		slog.String("holdingQueue", q.HoldingQueue),
+		slog.Int("bindings", len(q.Bindings)),
+		slog.String("operationID", opJournal.ID()),
+	)
+	return nil
+}
+
+// unquiesce restores the bindings of the source queue and appends the held messages to it. Nothing is done if the operation is not quiesced.
+func unquiesce(c *cli.Context, opJournal *journal.Journal) error {
+	op := opJournal.Operation()
+	q := op.Quiesce
+	if q == nil {
+		return nil
+	}
+
+	consumer, err := rabbitmq.NewSimpleConsumer(c.String("endpoint"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := consumer.Close()
+		if closeErr != nil {
+			log.Error("error while closing consumer", slog.Any("error", closeErr))
+		}
+	}()
+
+	mover := managers.NewQueueManager(consumer, log, handlers.NewPurgeHandler(), util.GetPublisher(c), selectors.NewNoSelector(), q.HoldingQueue).
+		WithMessageCounter(consumer).
+		WithIdleTimeout(c.Duration("idle-timeout"))
+
+	// held messages are appended while the traffic is still held, so that they stay in front of the new messages
+	if err = mover.Restore(c.Context, op.SrcQueue); err != nil {
+		return err
+	}
+	if err = moveBindings(util.GetClient(c), q, q.HoldingQueue, op.SrcQueue); err != nil {
+		return err
+	}
+	// append messages published while the bindings were moved
+	if err = mover.Restore(c.Context, op.SrcQueue); err != nil {
+		return err
+	}
+
+	if err = util.GetClient(c).DeleteQueueIfEmpty(q.Vhost, q.HoldingQueue); err != nil {
+		log.Warn("failed to delete holding queue. If needed, please manually delete holding queue", slog.Any("error", err), slog.String("queue", q.HoldingQueue))
+	}
+	if err = opJournal.Update(func(op *journal.Operation) { op.Quiesce = nil }); err != nil {
+		return err
+	}
+	log.Info("source queue traffic is restored", slog.String("operationID", opJournal.ID()))
+	return nil
+}
+
+// moveBindings binds the destination queue to the recorded exchanges before unbinding the source queue, so that no message is lost.
+// Messages published while a binding is moved may be routed to both queues.
+func moveBindings(client *rabbitmq.Client, q *journal.Quiesce, from, to string) error {
+	for _, binding := range q.Bindings {
+		err := client.DeclareBinding(q.Vhost, binding.Exchange, to, binding.RoutingKey, binding.Arguments)
+		if err != nil {
+			return err
+		}
+	}
+
+	existing, err := client.ListQueueBindings(q.Vhost, from)
+	if err != nil {
+		return err
+	}
+	for _, info := range existing {
+		for _, binding := range q.Bindings {
+			if info.Source == binding.Exchange && info.RoutingKey == binding.RoutingKey && sameArguments(info.Arguments, binding.Arguments) {
+				if err = client.DeleteBinding(q.Vhost, info); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func sameArguments(a, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func declareHoldingQueue(endpoint string) (string, error) {
+	connection, err := amqp091.Dial(endpoint)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		closeErr := connection.Close()
+		if closeErr != nil {
+			log.Error("error while closing connection", slog.Any("error", closeErr))
+		}
+	}()
+
+	channel, err := connection.Channel()
+	if err != nil {
+		return "", err
+	}
+	queue, err := channel.QueueDeclare("", true, false, false, false, nil)
+	if err != nil {
+		return "", err
+	}
+	return queue.Name, nil
+}
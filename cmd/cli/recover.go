@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/managers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/recovery"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/rabbitmq"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
+)
+
+func recoverQueues() *cli.Command {
+	return &cli.Command{
+		Name:  "recover",
+		Usage: "Recover queues after a failed operation",
+		Description: `Brings the source and temporary queue of a failed operation back into a consistent state.
+Inspects the front of the queues, removes the duplicate left by a failed acknowledgment and moves the temporary queue messages back to the source queue, preserving the order.
+Remaining source queue messages are not processed by the failed command. Use "resume" command instead to continue processing them.
+The operation is described either by the operation journal (--operation) or by the flags taken from the error log.`,
+		UsageText: `rabbitmq-cli recover [command options]
+Example: rabbitmq-cli recover --operation <operationID>
+Example: rabbitmq-cli -q <srcQueueName> -t <tempQueueName> recover --phase sourceToTemp --failed-step ack --message-id <messageID>`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "operation",
+				Usage: "ID of the failed operation. If provided, queues and the last processed message are read from the operation journal.",
+			},
+			&cli.StringFlag{
+				Name:    "destination",
+				Aliases: []string{"d"},
+				Usage:   "Name of the destination queue of the failed move/copy command.",
+			},
+			&cli.StringFlag{
+				Name:  "message-id",
+				Usage: "Message ID of the last processed message (the one that caused the error).",
+			},
+			&cli.StringFlag{
+				Name:  "phase",
+				Usage: "Phase in which the operation failed (sourceToTemp, tempToSource).",
+				Value: string(journal.PhaseSourceToTemp),
+			},
+			&cli.StringFlag{
+				Name:  "failed-step",
+				Usage: "Step in which processing of the last message failed (select, handle, archive, publishTemp, confirm, ack, publishSource, ackTemp, consume).",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "Inspect the queues and execute the recovery plan without asking for confirmation.",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			op, opJournal, err := recoveryOperation(c)
+			if err != nil {
+				return err
+			}
+
+			// the front of the queues is inspected by getting and requeueing the message, so it is confirmed before anything is touched
+			_, _ = fmt.Fprintf(c.App.Writer, "The front of the source queue %v and the temporary queue %v is inspected by getting the message and requeueing it.\n", op.SrcQueue, op.TempQueue)
+			client := util.GetClient(c)
+			for _, queue := range []string{op.SrcQueue, op.TempQueue} {
+				queueInfo, err := client.GetQueueInfo(queue)
+				if err != nil {
+					return err
+				}
+				if queueInfo.Type == amqp091.QueueTypeQuorum {
+					_, _ = fmt.Fprintln(c.App.ErrWriter, quorumInspectionWarning(queue, queueInfo.Arguments["x-delivery-limit"]))
+				}
+			}
+			if !c.Bool("yes") {
+				confirmed, err := confirm(c, "Inspect the queues? [y/N]: ")
+				if err != nil {
+					return err
+				}
+				if !confirmed {
+					return errors.New("recovery cancelled")
+				}
+			}
+
+			consumer, err := rabbitmq.NewSimpleConsumer(c.String("endpoint"))
+			if err != nil {
+				return err
+			}
+			defer func() {
+				closeErr := consumer.Close()
+				if closeErr != nil {
+					log.Error("error while closing consumer", slog.Any("error", closeErr))
+				}
+			}()
+
+			var state recovery.State
+			state.Operation = op
+			if state.SourceHead, err = peekHead(consumer, op.SrcQueue); err != nil {
+				return err
+			}
+			if state.TempHead, err = peekHead(consumer, op.TempQueue); err != nil {
+				return err
+			}
+
+			plan := recovery.NewPlan(state)
+			printPlan(c, op, plan)
+			if len(plan.Steps) == 0 && op.Quiesce == nil {
+				return nil
+			}
+			if !c.Bool("yes") {
+				confirmed, err := confirm(c, "Proceed with recovery? [y/N]: ")
+				if err != nil {
+					return err
+				}
+				if !confirmed {
+					return errors.New("recovery cancelled")
+				}
+			}
+
+			for _, step := range plan.Steps {
+				switch step.Action {
+				case recovery.ActionRemoveSourceHead:
+					err = removeHead(consumer, op.SrcQueue, op.LastProcessedMessage)
+				case recovery.ActionRemoveTempHead:
+					err = removeHead(consumer, op.TempQueue, op.LastProcessedMessage)
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			if len(plan.Steps) == 0 {
+				return unquiesce(c, opJournal)
+			}
+
+			_, cleanup, err := handleTempQueue(c.String("endpoint"), op.TempQueue, false, nil)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			// remaining source queue messages are not selected, so they are only moved behind the already processed messages
+			manager := managers.NewQueueManager(consumer, log, handlers.NewPurgeHandler(), util.GetPublisher(c), selectors.NewNoSelector(), op.TempQueue).
+				WithJournal(opJournal).
+				WithMessageCounter(consumer).
+				WithIdleTimeout(c.Duration("idle-timeout"))
+			if plan.Contains(recovery.ActionMoveSourceToTemp) {
+				err = manager.Manage(c.Context, op.SrcQueue)
+			} else {
+				err = manager.Restore(c.Context, op.SrcQueue)
+			}
+			if err != nil {
+				return err
+			}
+			return unquiesce(c, opJournal)
+		},
+	}
+}
+
+// recoveryOperation describes the failed operation, either from the operation journal or from the flags.
+func recoveryOperation(c *cli.Context) (journal.Operation, *journal.Journal, error) {
+	if id := c.String("operation"); id != "" {
+		opJournal, err := journal.Open(c.String("journal-dir"), id)
+		if err != nil {
+			return journal.Operation{}, nil, err
+		}
+		if op := opJournal.Operation(); op.Spool != "" {
+			// spooled messages are not in a queue, the spool replay continues from its cursor
+			return journal.Operation{}, nil, fmt.Errorf(`operation %v keeps messages in spool %v, please use "resume" command`, op.ID, op.Spool)
+		}
+		return opJournal.Operation(), opJournal, nil
+	}
+
+	op := journal.Operation{
+		SrcQueue:   c.String("queue"),
+		TempQueue:  c.String("temp-queue"),
+		Phase:      journal.Phase(c.String("phase")),
+		FailedStep: journal.Step(c.String("failed-step")),
+		Args:       map[string]string{"destination": c.String("destination")},
+	}
+	if messageID := c.String("message-id"); messageID != "" {
+		op.LastProcessedMessage = &journal.MessageIdentity{MessageID: messageID}
+	}
+	if op.SrcQueue == "" || op.TempQueue == "" {
+		return journal.Operation{}, nil, errors.New(`either "operation" or both "queue" and "temp-queue" flags must be set`)
+	}
+	return op, nil, nil
+}
+
+// quorumInspectionWarning describes the effect of inspecting the front of a quorum queue.
+func quorumInspectionWarning(queue string, deliveryLimit interface{}) string {
+	warning := fmt.Sprintf("WARNING: inspecting the front of the quorum queue %v increments the delivery count of the message.", queue)
+	if deliveryLimit != nil {
+		warning += fmt.Sprintf(" The queue has a delivery limit of %v, a message which reaches it is dead-lettered or dropped.", deliveryLimit)
+	}
+	return warning
+}
+
+func printPlan(c *cli.Context, op journal.Operation, plan recovery.Plan) {
+	w := c.App.Writer
+	_, _ = fmt.Fprintf(w, "Recovery plan (source queue: %v, temporary queue: %v, phase: %v, failed step: %v)\n", op.SrcQueue, op.TempQueue, op.Phase, op.FailedStep)
+	if len(plan.Steps) == 0 && op.Quiesce == nil {
+		_, _ = fmt.Fprintln(w, "Nothing to recover.")
+	}
+	for i, step := range plan.Steps {
+		_, _ = fmt.Fprintf(w, "  %v. %v\n", i+1, step.Description)
+	}
+	if op.Quiesce != nil {
+		_, _ = fmt.Fprintf(w, "  %v. Restore bindings of the source queue %v and append messages held in the holding queue %v.\n", len(plan.Steps)+1, op.SrcQueue, op.Quiesce.HoldingQueue)
+	}
+	for _, warning := range plan.Warnings {
+		_, _ = fmt.Fprintf(w, "  WARNING: %v\n", warning)
+	}
+}
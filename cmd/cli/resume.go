@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/plugins"
+)
+
+func resumeOperation() *cli.Command {
+	return &cli.Command{
+		Name:        "resume",
+		Usage:       "Resume a failed operation",
+		Description: "Resumes a failed or cancelled operation from the phase in which it stopped, using the operation journal. Operation ID is logged when the operation fails.",
+		ArgsUsage:   "<operationID>",
+		UsageText: `rabbitmq-cli resume <operationID>
+Example: rabbitmq-cli resume 20240101T120000-1a2b3c4d`,
+		Action: func(c *cli.Context) error {
+			id := c.Args().First()
+			if id == "" {
+				return errors.New("operation ID is required")
+			}
+			if c.Bool("unordered") {
+				return errors.New("unordered mode does not support resuming operations")
+			}
+
+			opJournal, err := journal.Open(c.String("journal-dir"), id)
+			if err != nil {
+				return err
+			}
+			op := opJournal.Operation()
+			if op.Phase == journal.PhaseFinished {
+				if op.Quiesce != nil {
+					// operation finished, but the source queue traffic was not restored
+					return unquiesce(c, opJournal)
+				}
+				return fmt.Errorf("operation %v is already finished", op.ID)
+			}
+
+			if op.Plugin != "" {
+				// the journaled plugin is used, so that messages are selected and handled as in the original operation
+				plugin, err := plugins.Load(c.Context, op.Plugin)
+				if err != nil {
+					return err
+				}
+				defer func() {
+					if closeErr := plugin.Close(c.Context); closeErr != nil {
+						log.Error("error while closing plugin", slog.Any("error", closeErr))
+					}
+				}()
+				util.AttachPlugin(c, plugin)
+			}
+
+			handler, err := operationHandler(c, op)
+			if err != nil {
+				return err
+			}
+			if closer, ok := handler.(io.Closer); ok {
+				defer func() {
+					if closeErr := closer.Close(); closeErr != nil {
+						log.Error("error while closing handler", slog.Any("error", closeErr))
+					}
+				}()
+			}
+			return runOperation(c, op, opJournal, handler)
+		},
+	}
+}
+
+// operationHandler builds the handler of the journaled operation.
+func operationHandler(c *cli.Context, op journal.Operation) (handlers.MessageHandler, error) {
+	switch op.Command {
+	case "view":
+		// viewed messages are printed to stdout, regardless of the original output
+		format := op.Args["format"]
+		if format == "" {
+			format = handlers.ViewFormatJSON
+		}
+		return handlers.NewViewHandler(math.MaxInt, nil).WithFormat(format), nil
+	case "move":
+		publisher, err := destinationPublisher(c, op.Args)
+		if err != nil {
+			return nil, err
+		}
+		handler := handlers.NewMoveHandler(publisher, op.Args["destination"])
+		return interactive(c, handler, "move to "+op.Args["destination"], op.Args["interactive"] == "true"), nil
+	case "copy":
+		handler, err := copyHandler(c, op.Args)
+		if err != nil {
+			return nil, err
+		}
+		return handler, nil
+	case "split":
+		handler, err := splitHandler(c, op.Args)
+		if err != nil {
+			return nil, err
+		}
+		return handler, nil
+	case "purge":
+		return interactive(c, handlers.NewPurgeHandler(), "purge", op.Args["interactive"] == "true"), nil
+	case "exec":
+		args, err := execArgs(op.Args)
+		if err != nil {
+			return nil, err
+		}
+		handler, err := startExec(c, args)
+		if err != nil {
+			return nil, err
+		}
+		return handler, nil
+	case "plugin":
+		handler, err := pluginHandler(c, util.GetPlugin(c))
+		if err != nil {
+			return nil, err
+		}
+		return handler, nil
+	case "webhook":
+		handler, err := webhookHandler(c, op.Args)
+		if err != nil {
+			return nil, err
+		}
+		return handler, nil
+	case "edit":
+		return handlers.NewEditHandler(messageEditor(c)), nil
+	case "undo", "browse":
+		// archived messages and browser marks are not journaled, so only moving the messages back to the source queue can be resumed
+		if op.Phase != journal.PhaseTempToSource {
+			return nil, fmt.Errorf(`operation %v of %v command failed while processing the source queue and cannot be resumed. Please restore the source queue with "recover" command and check which messages were processed`, op.ID, op.Command)
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("operation %v of %v command cannot be resumed", op.ID, op.Command)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
+)
+
+func splitMessages() *cli.Command {
+	return &cli.Command{
+		Name:  "split",
+		Usage: "Split messages from source queue into destination queues",
+		Description: `Moves selected messages to the destination queues (shards), round-robin or by consistent hash of the key expression.
+Messages with the same key are moved to the same shard in the order of the source queue. Round-robin does not keep any order across shards.
+The number of confirmed messages per destination queue is printed at the end.`,
+		UsageText: `rabbitmq-cli split [command options]
+Example: rabbitmq-cli -q <srcQueueName> split -d <destQueueName1> -d <destQueueName2> --key headers.customerId`,
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:     "destination",
+				Aliases:  []string{"d"},
+				Usage:    "Name of a destination queue, repeated for each shard.",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "key",
+				Usage: "Expression computing the key of each message (https://expr-lang.org/), e.g. headers.customerId. Messages are distributed round-robin if not provided.",
+			},
+			flagYes,
+			flagRate,
+			flagBurst,
+			flagMaxDestinationDepth,
+		},
+		Action: func(c *cli.Context) error {
+			destQueues := c.StringSlice("destination")
+			// check if destination queues exist
+			for _, destQueue := range destQueues {
+				if _, err := util.GetClient(c).GetQueueInfo(destQueue); err != nil {
+					return err
+				}
+			}
+			handler, err := splitHandler(c, commandArgs(c))
+			if err != nil {
+				return err
+			}
+			if err = manageQueue(c, handler); err != nil {
+				return err
+			}
+			printCounts(c, "Moved", handler.Counts())
+			return nil
+		},
+	}
+}
+
+// region Helpers
+
+// splitHandler builds the handler splitting messages into the destination queues from the (journaled) command arguments.
+func splitHandler(c *cli.Context, args map[string]string) (*handlers.SplitHandler, error) {
+	destQueues, err := argValues(args, "destination")
+	if err != nil {
+		return nil, err
+	}
+	if len(destQueues) < 2 {
+		return nil, errors.New("split command requires at least two destination queues")
+	}
+	for i, destQueue := range destQueues {
+		if slices.Contains(destQueues[i+1:], destQueue) {
+			return nil, fmt.Errorf("destination queue %v is provided more than once", destQueue)
+		}
+	}
+	publisher, err := destinationPublisher(c, args)
+	if err != nil {
+		return nil, err
+	}
+	handler := handlers.NewSplitHandler(publisher, destQueues...)
+	key := args["key"]
+	if key == "" {
+		return handler, nil
+	}
+
+	keyExpr, err := selectors.NewKeyExpr(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key expression: %w", err)
+	}
+	return handler.WithKeyExpr(keyExpr), nil
+}
+
+// endregion
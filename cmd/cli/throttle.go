@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/throttle"
+)
+
+var flagRate = &cli.Float64Flag{
+	Name:  "rate",
+	Usage: "Maximum number of messages published to destination queues per second. Not limited by default.",
+}
+
+var flagBurst = &cli.IntFlag{
+	Name:  "burst",
+	Usage: "Maximum number of messages published at once above the rate, e.g. after a pause.",
+	Value: 1,
+}
+
+var flagMaxDestinationDepth = &cli.IntFlag{
+	Name:  "max-destination-depth",
+	Usage: "Pause publishing while a destination queue holds more messages than the maximum, as reported by the HTTP API. Not limited by default.",
+}
+
+// destinationPublisher returns the publisher of messages published to destination queues, throttled as set by the (journaled) command arguments.
+func destinationPublisher(c *cli.Context, args map[string]string) (messaging.Publisher, error) {
+	if args["rate"] == "" && args["max-destination-depth"] == "" {
+		return util.GetPublisher(c), nil
+	}
+	publisher := throttle.NewPublisher(util.GetPublisher(c), log).
+		WithContext(c.Context).
+		WithStop(util.GetStop(c))
+
+	if args["rate"] != "" {
+		perSecond, err := strconv.ParseFloat(args["rate"], 64)
+		if err != nil || perSecond <= 0 {
+			return nil, fmt.Errorf("invalid rate %q", args["rate"])
+		}
+		burst := 1
+		if args["burst"] != "" {
+			if burst, err = strconv.Atoi(args["burst"]); err != nil || burst < 1 {
+				return nil, fmt.Errorf("invalid burst %q", args["burst"])
+			}
+		}
+		publisher.WithRate(perSecond, burst)
+	}
+	if args["max-destination-depth"] != "" {
+		maxDepth, err := strconv.Atoi(args["max-destination-depth"])
+		if err != nil || maxDepth < 0 {
+			return nil, fmt.Errorf("invalid max destination depth %q", args["max-destination-depth"])
+		}
+		publisher.WithMaxDepth(util.GetClient(c).MessageCount, maxDepth, 0)
+	}
+	return publisher, nil
+}
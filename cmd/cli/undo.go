@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/spool"
+)
+
+func undoOperation() *cli.Command {
+	return &cli.Command{
+		Name:  "undo",
+		Usage: "Undo removal of messages by a finished purge or move operation",
+		Description: `Inserts the messages archived by the operation back into the source queue at their original positions, preserving the order of the other messages.
+Messages moved to the destination queue are not removed from it. An operation can be undone only once.`,
+		ArgsUsage: "<operationID>",
+		UsageText: `rabbitmq-cli undo <operationID>
+Example: rabbitmq-cli undo 20240101T120000-1a2b3c4d`,
+		Action: func(c *cli.Context) error {
+			id := c.Args().First()
+			if id == "" {
+				return errors.New("operation ID is required")
+			}
+			if c.Bool("unordered") {
+				return errors.New("unordered mode does not support undo command")
+			}
+
+			opJournal, err := journal.Open(c.String("journal-dir"), id)
+			if err != nil {
+				return err
+			}
+			if err = checkUndoable(opJournal.Operation()); err != nil {
+				return err
+			}
+
+			op := journal.Operation{
+				Command:   "undo",
+				Args:      map[string]string{"operation": id},
+				SrcQueue:  opJournal.Operation().SrcQueue,
+				TempQueue: c.String("temp-queue"),
+			}
+			// archived messages are only inserted, so no message is handled
+			return runOperation(c, op, nil, nil)
+		},
+	}
+}
+
+// region Helpers
+
+// checkUndoable checks if removal of messages by the operation can be undone.
+func checkUndoable(op journal.Operation) error {
+	if op.Archive == "" {
+		return fmt.Errorf("operation %v has no archive of removed messages", op.ID)
+	}
+	if op.Phase != journal.PhaseFinished {
+		return fmt.Errorf(`operation %v is not finished. Please finish it with "resume" or "recover" command first`, op.ID)
+	}
+	if op.Undone {
+		return fmt.Errorf("operation %v is already undone", op.ID)
+	}
+	return nil
+}
+
+// startUndo marks the operation as undone and returns the reader of its archive.
+// The operation is marked before any message is inserted, so that a failed undo cannot be repeated and duplicate the messages.
+func startUndo(c *cli.Context, id string) (reader *spool.Reader, cleanup func(), err error) {
+	opJournal, err := journal.Open(c.String("journal-dir"), id)
+	if err != nil {
+		return nil, nil, err
+	}
+	op := opJournal.Operation()
+	if err = checkUndoable(op); err != nil {
+		return nil, nil, err
+	}
+
+	archive, err := spool.Open(op.Archive)
+	if err != nil {
+		return nil, nil, err
+	}
+	closeArchive := func() {
+		if closeErr := archive.Close(); closeErr != nil {
+			log.Error("error while closing archive", slog.Any("error", closeErr), slog.String("archive", op.Archive))
+		}
+	}
+
+	reader, err = archive.Reader()
+	if err == nil {
+		err = opJournal.Update(func(op *journal.Operation) { op.Undone = true })
+	}
+	if err != nil {
+		if reader != nil {
+			_ = reader.Close()
+		}
+		closeArchive()
+		return nil, nil, err
+	}
+
+	log.Info("inserting archived messages into the source queue", slog.String("archive", op.Archive), slog.String("operationID", id))
+	return reader, func() {
+		_ = reader.Close()
+		closeArchive()
+	}, nil
+}
+
+// endregion
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/managers"
+)
+
+const unorderedWarning = `WARNING: unordered mode does not preserve the order of messages in the source queue.
+Messages which are kept are held unacknowledged by this client until the end of the pass and then requeued,
+so other consumers cannot receive them in the meantime and queues with a delivery limit count the requeue as a delivery.`
+
+// runUnordered runs the operation in a single pass over the source queue, without the temporary queue and the journal.
+func runUnordered(c *cli.Context, op journal.Operation, handler handlers.MessageHandler) error {
+	supportedCommands := []string{"purge", "move", "copy"}
+	if !slices.Contains(supportedCommands, op.Command) {
+		return fmt.Errorf("unordered mode does not support %v command. Supported commands: %v", op.Command, strings.Join(supportedCommands, ","))
+	}
+	for _, flag := range []string{"temp-queue", "quiesce", "spool", "interactive", "replay"} {
+		if c.IsSet(flag) {
+			return fmt.Errorf(`"unordered" and %q flags cannot be used together`, flag)
+		}
+	}
+	if op.Command != "copy" && !c.Bool("no-archive") {
+		return errors.New(`unordered mode does not archive removed messages, so the operation cannot be undone. Please set "no-archive" flag to confirm`)
+	}
+
+	queueInfo, err := util.GetClient(c).GetQueueInfo(op.SrcQueue)
+	if err != nil {
+		return err
+	}
+	if queueInfo.Type == amqp091.QueueTypeStream {
+		return fmt.Errorf("%v queue type does not support unordered mode", amqp091.QueueTypeStream)
+	}
+	destinations, err := argValues(op.Args, "destination")
+	if err != nil {
+		return err
+	}
+	if err = preflight(c, op, destinations...); err != nil {
+		return err
+	}
+	if err = confirmImpact(c, op); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintln(c.App.ErrWriter, unorderedWarning)
+	log.Warn("running in unordered mode, the order of messages in the source queue is not preserved", slog.String("srcQueue", op.SrcQueue))
+
+	// prefetch is not limited by the unordered manager, since kept messages are held unacknowledged until the end of the pass
+	consumer, err := consumerFactory(queueInfo.Type, c.String("endpoint"), 0, c.Bool("exclusive"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := consumer.Close()
+		if closeErr != nil {
+			log.Error("error while closing consumer", slog.Any("error", closeErr))
+		}
+	}()
+
+	selector, err := buildSelector(c, op)
+	if err != nil {
+		return err
+	}
+	counter, ok := consumer.(messaging.MessageCounter)
+	if !ok {
+		return errors.New("consumer cannot count messages in the source queue")
+	}
+
+	return managers.NewUnorderedManager(consumer, log, handler, util.GetPublisher(c), selector).
+		WithMessageCounter(counter).
+		WithIdleTimeout(c.Duration("idle-timeout")).
+		WithStop(util.GetStop(c)).
+		Manage(c.Context, op.SrcQueue)
+}
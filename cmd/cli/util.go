@@ -1,12 +1,23 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
 	"net/url"
+	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/urfave/cli/v2"
@@ -14,18 +25,60 @@ import (
 	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/managers"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/rabbitmq"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/spool"
 )
 
+// spoolPrefetch is the minimal number of prefetched source messages when messages are kept in the spool.
+// Spooled messages are synced to disk in batches of unacknowledged messages, so a larger window avoids syncing each message separately.
+const spoolPrefetch = 256
+
+// archiveFileExtension is the extension of archives of removed messages, stored next to the operation journal.
+const archiveFileExtension = ".archive"
+
+// removingCommands are commands which may remove messages from the source queue, so their removed messages are archived.
+var removingCommands = []string{"purge", "move", "split", "browse", "exec", "plugin", "webhook"}
+
+// mirroredTempQueueArgs are source queue arguments applied to the temporary queue.
+var mirroredTempQueueArgs = []string{"x-max-priority", "x-queue-mode", "x-queue-version", "x-quorum-initial-group-size", "x-queue-leader-locator", "x-queue-master-locator"}
+
+type resumableManager interface {
+	Resume(ctx context.Context, srcQueue string) error
+}
+
 // region Helpers
 
 func manageQueue(c *cli.Context, handler handlers.MessageHandler) error {
+	op := journal.Operation{
+		Command:   c.Command.Name,
+		Args:      journalArgs(c),
+		Filter:    c.String("filter"),
+		Plugin:    c.String("plugin"),
+		SrcQueue:  c.String("queue"),
+		TempQueue: c.String("temp-queue"),
+	}
+	if op.SrcQueue == "" {
+		return errors.New(`required flag "queue" not set`)
+	}
+	if c.Bool("unordered") {
+		return runUnordered(c, op, handler)
+	}
+	return runOperation(c, op, nil, handler)
+}
+
+// runOperation runs the operation with the provided handler. If opJournal is provided, the journaled operation is resumed.
+func runOperation(c *cli.Context, op journal.Operation, opJournal *journal.Journal, handler handlers.MessageHandler) error {
 	endpoint := c.String("endpoint")
-	tempQueue := c.String("temp-queue")
-	filterExpr := c.String("filter")
-	srcQueue := c.String("queue")
+	tempQueue := op.TempQueue
+	srcQueue := op.SrcQueue
+	resume := opJournal != nil
+	useSpool := c.Bool("spool") || op.Spool != ""
+	prefetch := c.Int("confirm-window")
+	var opSpool, archive *spool.Spool
+	var insertions *spool.Reader
 
 	queueInfo, err := util.GetClient(c).GetQueueInfo(srcQueue)
 	if err != nil {
@@ -35,21 +88,97 @@ func manageQueue(c *cli.Context, handler handlers.MessageHandler) error {
 	// perform additional logic before creating and running manager
 	switch queueInfo.Type {
 	case amqp091.QueueTypeClassic, amqp091.QueueTypeQuorum:
-		// create a temporary queue to preserve the original order of messages in the source queue
-		var cleanup func()
-		tempQueue, cleanup, err = handleTempQueue(endpoint, tempQueue)
-		if err != nil {
+		if useSpool && tempQueue != "" {
+			return errors.New(`"spool" and "temp-queue" flags cannot be used together`)
+		}
+		if op.Args["replay"] == "true" {
+			return fmt.Errorf("%v queue type does not support replay", queueInfo.Type)
+		}
+		var destinations []string
+		if destinations, err = argValues(op.Args, "destination"); err != nil {
+			return err
+		}
+		if err = preflight(c, op, append(destinations, tempQueue)...); err != nil {
 			return err
 		}
-		defer cleanup()
+		if !resume {
+			if err = confirmImpact(c, op); err != nil {
+				return err
+			}
+		}
+
+		if !useSpool {
+			// create a temporary queue to preserve the original order of messages in the source queue
+			var cleanup func()
+			tempQueue, cleanup, err = handleTempQueue(endpoint, tempQueue, resume, tempQueueArgs(queueInfo.Type, queueInfo.Arguments))
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			reportTempQueuePolicies(c, queueInfo.Vhost, tempQueue, queueInfo.Type)
+		}
+
+		if !resume {
+			op.TempQueue = tempQueue
+			opJournal, err = journal.New(c.String("journal-dir"), op)
+			if err != nil {
+				return err
+			}
+			log.Info("operation journal created", slog.String("operationID", opJournal.ID()), slog.String("journal", opJournal.Path()))
+		}
+
+		if useSpool {
+			// keep messages in a local spool next to the journal to preserve the original order of messages in the source queue
+			var cleanup func()
+			opSpool, cleanup, err = handleSpool(opJournal, resume)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			prefetch = max(prefetch, spoolPrefetch)
+		}
+
+		if archiveRemoved(c, opJournal.Operation(), resume) {
+			// archive removed messages next to the journal, so that the operation can be undone
+			var cleanup func()
+			archive, cleanup, err = handleArchive(opJournal, resume)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+		}
+
+		if op.Command == "undo" && !resume {
+			var cleanup func()
+			insertions, cleanup, err = startUndo(c, op.Args["operation"])
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+		}
+
+		if c.Bool("quiesce") || opJournal.Operation().Quiesce != nil {
+			if err = quiesce(c, opJournal, queueInfo.Vhost); err != nil {
+				return err
+			}
+		}
 	case amqp091.QueueTypeStream:
 		supportedCommands := []string{"view", "copy"}
-		if !slices.Contains(supportedCommands, c.Command.Name) {
-			return fmt.Errorf("%v queue type does not support %v command. Supported commands: %v", amqp091.QueueTypeStream, c.Command.Name, strings.Join(supportedCommands, ","))
+		if !slices.Contains(supportedCommands, op.Command) {
+			return fmt.Errorf("%v queue type does not support %v command. Supported commands: %v", amqp091.QueueTypeStream, op.Command, strings.Join(supportedCommands, ","))
+		}
+		if resume {
+			return fmt.Errorf("%v queue type does not support resuming operations", amqp091.QueueTypeStream)
+		}
+		if c.Bool("quiesce") {
+			return fmt.Errorf("%v queue type does not support quiesce mode", amqp091.QueueTypeStream)
+		}
+		if useSpool {
+			return fmt.Errorf("%v queue type does not support spool", amqp091.QueueTypeStream)
 		}
 	}
 
-	consumer, err := consumerFactory(queueInfo.Type, endpoint)
+	consumer, err := consumerFactory(queueInfo.Type, endpoint, prefetch, c.Bool("exclusive"))
 	if err != nil {
 		return err
 	}
@@ -60,20 +189,49 @@ func manageQueue(c *cli.Context, handler handlers.MessageHandler) error {
 		}
 	}()
 
-	var selector selectors.Selector
-	if filterExpr != "" {
-		selector, err = selectors.NewFilterExprSelector(filterExpr)
-		if err != nil {
-			return err
-		}
+	selector, err := buildSelector(c, op)
+	if err != nil {
+		return err
+	}
+	if op.Command == "undo" {
+		// archived messages are only inserted, source queue messages are kept
+		selector = selectors.NewNoSelector()
+	}
+
+	// queue depth is read over AMQP for queues, stream depth is only available from the HTTP API
+	var counter messaging.MessageCounter = util.GetClient(c)
+	if queueCounter, ok := consumer.(messaging.MessageCounter); ok && queueInfo.Type != amqp091.QueueTypeStream {
+		counter = queueCounter
 	} else {
-		selector = selectors.NewYesSelector()
+		log.Warn("number of messages is read from the HTTP API, which refreshes queue statistics periodically. "+
+			"If the number is stale, processing may end before recently published messages, or only after the idle timeout",
+			slog.String("srcQueue", op.SrcQueue),
+			slog.Duration("idleTimeout", c.Duration("idle-timeout")),
+		)
 	}
 
-	manager, err := managerFactory(queueInfo.Type, consumer, util.GetPublisher(c), handler, selector, tempQueue)
+	manager, err := managerFactory(queueInfo.Type, consumer, util.GetPublisher(c), handler, selector, tempQueue, opJournal, counter, c.Duration("idle-timeout"), maxPriority(queueInfo.Arguments), util.GetStop(c))
 	if err != nil {
 		return err
 	}
+	if queueManager, ok := manager.(*managers.QueueManager); ok {
+		if opSpool != nil {
+			queueManager.WithSpool(opSpool)
+		}
+		if archive != nil {
+			queueManager.WithArchive(archive)
+		}
+		if insertions != nil {
+			queueManager.WithInsertions(insertions)
+		}
+	}
+	if streamManager, ok := manager.(*managers.StreamManager); ok && op.Args["replay"] == "true" {
+		replay, err := streamReplay(op.Args)
+		if err != nil {
+			return err
+		}
+		streamManager.WithReplay(replay)
+	}
 
 	log.Info("source queue messages info",
 		slog.Int("total", queueInfo.Messages),
@@ -81,10 +239,108 @@ func manageQueue(c *cli.Context, handler handlers.MessageHandler) error {
 		slog.Int("unacknowledged", queueInfo.MessagesUnacknowledged),
 	)
 
-	return manager.Manage(c.Context, srcQueue)
+	if resumable, ok := manager.(resumableManager); ok && resume {
+		err = resumable.Resume(c.Context, srcQueue)
+	} else {
+		err = manager.Manage(c.Context, srcQueue)
+	}
+	if err != nil {
+		if q := opJournal.Operation().Quiesce; q != nil {
+			_, _ = fmt.Fprintf(c.App.ErrWriter, "WARNING: source queue traffic is held in the holding queue %v until operation %v is finished with \"resume\" or \"recover\" command.\n",
+				q.HoldingQueue, opJournal.ID())
+		}
+		return err
+	}
+	return unquiesce(c, opJournal)
+}
+
+// commandArgs returns the values of the command flags set by the user, and the command of exec given as arguments.
+func commandArgs(c *cli.Context) map[string]string {
+	args := make(map[string]string)
+	for _, flag := range c.Command.Flags {
+		name := flag.Names()[0]
+		if !c.IsSet(name) {
+			continue
+		}
+		if _, ok := flag.(*cli.StringSliceFlag); ok {
+			// repeated values are journaled as a JSON array, see argValues
+			values, _ := json.Marshal(c.StringSlice(name))
+			args[name] = string(values)
+			continue
+		}
+		args[name] = fmt.Sprint(c.Value(name))
+	}
+	if c.Command.Name == "exec" && c.Args().Present() {
+		// the command of exec can be given as arguments, which are journaled like repeated values
+		values, _ := json.Marshal(c.Args().Slice())
+		args["argv"] = string(values)
+	}
+	return args
+}
+
+// journalArgs returns the command arguments stored in the operation journal, with literal values of sensitive webhook headers redacted.
+func journalArgs(c *cli.Context) map[string]string {
+	args := commandArgs(c)
+	if c.Command.Name == "webhook" && args["header"] != "" {
+		headers, _ := argValues(args, "header")
+		values, _ := json.Marshal(redactHeaders(headers))
+		args["header"] = string(values)
+	}
+	return args
+}
+
+// streamReplay returns the replay settings of the copy command.
+func streamReplay(args map[string]string) (managers.Replay, error) {
+	replay := managers.Replay{Speed: 1}
+	if args["replay-speed"] != "" {
+		speed, err := strconv.ParseFloat(args["replay-speed"], 64)
+		if err != nil || speed <= 0 {
+			return managers.Replay{}, fmt.Errorf("invalid replay speed %q", args["replay-speed"])
+		}
+		replay.Speed = speed
+	}
+	maxGap, err := durationArg(args, "replay-max-gap", 0)
+	if err != nil {
+		return managers.Replay{}, err
+	}
+	replay.MaxGap = maxGap
+	replay.TimestampHeader = replayDefaultTimestampHeader
+	if header, ok := args["replay-timestamp-header"]; ok {
+		// an empty header disables it, so that only the timestamp property is used
+		replay.TimestampHeader = header
+	}
+	return replay, nil
 }
 
-func handleTempQueue(endpoint, queue string) (tempQueue string, cleanup func(), err error) {
+// durationArg returns the journaled duration flag, or the default value if the flag was not set.
+func durationArg(args map[string]string, name string, defaultValue time.Duration) (time.Duration, error) {
+	if args[name] == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(args[name])
+	if err != nil {
+		return 0, fmt.Errorf("invalid %q flag: %w", name, err)
+	}
+	return d, nil
+}
+
+// argValues returns the journaled values of a repeated flag. Values of flags which were not repeated are returned as a single value.
+func argValues(args map[string]string, name string) ([]string, error) {
+	var values []string
+	if args[name] == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(args[name], "[") {
+		return []string{args[name]}, nil
+	}
+	if err := json.Unmarshal([]byte(args[name]), &values); err != nil {
+		return nil, fmt.Errorf("invalid journaled %q flag: %w", name, err)
+	}
+	return values, nil
+}
+
+// handleTempQueue creates a new temporary queue or checks if the provided one exists. If declare is set, the provided queue is declared with the provided arguments if it does not exist.
+func handleTempQueue(endpoint, queue string, declare bool, args amqp091.Table) (tempQueue string, cleanup func(), err error) {
 	connection, err := amqp091.Dial(endpoint)
 	if err != nil {
 		return "", nil, err
@@ -108,13 +364,25 @@ func handleTempQueue(endpoint, queue string) (tempQueue string, cleanup func(),
 	}
 
 	if queue == "" {
-		tQueue, err := channel.QueueDeclare(queue, true, false, false, false, nil)
+		// quorum queues cannot be server-named
+		queue, err = tempQueueName()
 		if err != nil {
 			return "", nil, err
 		}
-		queue = tQueue.Name
+		if _, err = channel.QueueDeclare(queue, true, false, false, false, args); err != nil {
+			return "", nil, err
+		}
 	} else {
+		// existing queue is used as it is, since its arguments may differ from the current ones (redeclaring it would fail with PRECONDITION_FAILED)
 		_, err = channel.QueueDeclarePassive(queue, true, false, false, false, nil)
+		var amqpErr *amqp091.Error
+		if err != nil && declare && errors.As(err, &amqpErr) && amqpErr.Code == amqp091.NotFound {
+			// failed passive declaration closes the channel
+			if channel, err = connection.Channel(); err != nil {
+				return "", nil, err
+			}
+			_, err = channel.QueueDeclare(queue, true, false, false, false, args)
+		}
 		if err != nil {
 			return "", nil, err
 		}
@@ -133,27 +401,251 @@ func handleTempQueue(endpoint, queue string) (tempQueue string, cleanup func(),
 	}, nil
 }
 
+// handleSpool creates the spool of the journaled operation or opens the existing one if the operation is resumed.
+// The spool is removed once the operation is finished, otherwise it is kept for resuming the operation.
+func handleSpool(opJournal *journal.Journal, resume bool) (opSpool *spool.Spool, cleanup func(), err error) {
+	path := opJournal.Operation().Spool
+	if path == "" {
+		path = filepath.Join(filepath.Dir(opJournal.Path()), opJournal.ID()+spool.FileExtension)
+		if err = opJournal.Update(func(op *journal.Operation) { op.Spool = path }); err != nil {
+			return nil, nil, err
+		}
+	} else if _, err = os.Stat(path); resume && err != nil {
+		// spool holds messages already removed from the source queue, so a resumed operation cannot continue without it
+		return nil, nil, fmt.Errorf("spool of operation %v cannot be opened: %w", opJournal.ID(), err)
+	}
+
+	opSpool, err = spool.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return opSpool, func() {
+		if closeErr := opSpool.Close(); closeErr != nil {
+			log.Error("error while closing spool", slog.Any("error", closeErr), slog.String("spool", path))
+		}
+		if opJournal.Operation().Phase != journal.PhaseFinished {
+			log.Warn(`spool is kept until the operation is finished with "resume" command`, slog.String("spool", path), slog.String("operationID", opJournal.ID()))
+			return
+		}
+		if removeErr := spool.Remove(path); removeErr != nil {
+			log.Warn("failed to remove spool. If needed, please manually delete the spool", slog.Any("error", removeErr), slog.String("spool", path))
+		}
+	}, nil
+}
+
+// archiveRemoved reports whether messages removed by the operation are archived. Resumed operations keep archiving only if they started with it.
+func archiveRemoved(c *cli.Context, op journal.Operation, resume bool) bool {
+	if resume {
+		return op.Archive != ""
+	}
+	return slices.Contains(removingCommands, op.Command) && !c.Bool("no-archive")
+}
+
+// handleArchive creates the archive of the journaled operation or opens the existing one if the operation is resumed.
+// The archive is kept after the operation is finished, so that the operation can be undone.
+func handleArchive(opJournal *journal.Journal, resume bool) (archive *spool.Spool, cleanup func(), err error) {
+	path := opJournal.Operation().Archive
+	if path == "" {
+		path = filepath.Join(filepath.Dir(opJournal.Path()), opJournal.ID()+archiveFileExtension)
+		if err = opJournal.Update(func(op *journal.Operation) { op.Archive = path }); err != nil {
+			return nil, nil, err
+		}
+	} else if _, err = os.Stat(path); resume && err != nil {
+		// messages archived before the failure would be lost for undo
+		return nil, nil, fmt.Errorf("archive of operation %v cannot be opened: %w", opJournal.ID(), err)
+	}
+
+	archive, err = spool.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Info("removed messages are archived", slog.String("archive", path))
+	return archive, func() {
+		if closeErr := archive.Close(); closeErr != nil {
+			log.Error("error while closing archive", slog.Any("error", closeErr), slog.String("archive", path))
+		}
+	}, nil
+}
+
+// tempQueueArgs mirrors the source queue type and the arguments which affect how messages are stored and delivered.
+// Arguments which could expire, drop or dead-letter messages while they are in the temporary queue are left out.
+func tempQueueArgs(queueType string, srcArgs map[string]interface{}) amqp091.Table {
+	args := amqp091.Table{amqp091.QueueTypeArg: queueType}
+	var dropped []string
+	for name, value := range srcArgs {
+		switch {
+		case name == amqp091.QueueTypeArg:
+		case slices.Contains(mirroredTempQueueArgs, name):
+			// numbers read from the HTTP API are decoded as floats, but the broker expects integers
+			if number, ok := value.(float64); ok && number == math.Trunc(number) {
+				value = int64(number)
+			}
+			args[name] = value
+		default:
+			dropped = append(dropped, name)
+		}
+	}
+	if len(dropped) > 0 {
+		slices.Sort(dropped)
+		log.Info("source queue arguments not applied to the temporary queue", slog.Any("arguments", dropped))
+	}
+	return args
+}
+
+// reportTempQueuePolicies warns about policies which apply to the temporary queue, since they may expire, drop or dead-letter its messages.
+func reportTempQueuePolicies(c *cli.Context, vhost, tempQueue, queueType string) {
+	policies, err := util.GetClient(c).ListMatchingPolicies(vhost, tempQueue, queueType)
+	if err != nil {
+		_, _ = fmt.Fprintf(c.App.ErrWriter, "WARNING: failed to check policies of the temporary queue %v: %v\n", tempQueue, err)
+		return
+	}
+	for _, policy := range policies {
+		kind := "policy"
+		if policy.Operator {
+			kind = "operator policy"
+		}
+		definition, _ := json.Marshal(policy.Definition)
+		_, _ = fmt.Fprintf(c.App.ErrWriter, "WARNING: %v %v applies to the temporary queue %v, please make sure it doesn't expire, drop or dead-letter messages: %s\n",
+			kind, policy.Name, tempQueue, definition)
+	}
+}
+
+// maxPriority returns the x-max-priority argument of the queue, 0 if the queue is not a priority queue.
+func maxPriority(args map[string]interface{}) int {
+	// numbers read from the HTTP API are decoded as floats
+	if priority, ok := args["x-max-priority"].(float64); ok {
+		return int(priority)
+	}
+	return 0
+}
+
+func tempQueueName() (string, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return "rabbitmq-message-ops.tmp." + hex.EncodeToString(suffix), nil
+}
+
+// peekHead returns the message at the front of the queue, without changing the order of the queue.
+// The message is got and requeued, so its delivery count is incremented in quorum queues (see x-delivery-limit).
+func peekHead(consumer *rabbitmq.Consumer, queue string) (*amqp091.Delivery, error) {
+	msg, ok, err := consumer.Get(queue)
+	if err != nil || !ok {
+		return nil, err
+	}
+	// requeued message is placed back to its original position
+	if err = msg.Nack(false, true); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// removeHead removes the message at the front of the queue if it matches the provided identity.
+func removeHead(consumer *rabbitmq.Consumer, queue string, identity *journal.MessageIdentity) error {
+	msg, ok, err := consumer.Get(queue)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("queue %v is empty", queue)
+	}
+	if !identity.Matches(msg) {
+		if err = msg.Nack(false, true); err != nil {
+			return err
+		}
+		return fmt.Errorf("message at the front of the queue %v has changed", queue)
+	}
+	return msg.Ack(false)
+}
+
+// confirm asks the user for confirmation and reports whether the answer is yes.
+func confirm(c *cli.Context, question string) (bool, error) {
+	answer, err := prompt(c, question)
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
+
+// prompt asks the user for a single line answer. Missing answer is reported as empty.
+// The app reader is buffered (see buildCLIApp), bufio.NewReader then returns it, so that buffered input is kept for the next prompt.
+// The answer is read in the background, so that an interrupt or an abort cancels the prompt.
+func prompt(c *cli.Context, question string) (string, error) {
+	_, _ = fmt.Fprint(c.App.Writer, question)
+	type result struct {
+		answer string
+		err    error
+	}
+	results := make(chan result, 1)
+	go func() {
+		answer, err := bufio.NewReader(c.App.Reader).ReadString('\n')
+		results <- result{answer: answer, err: err}
+	}()
+
+	select {
+	case r := <-results:
+		if r.err != nil && !errors.Is(r.err, io.EOF) {
+			return "", r.err
+		}
+		return strings.TrimSpace(r.answer), nil
+	case <-util.GetStop(c):
+		_, _ = fmt.Fprintln(c.App.Writer)
+		return "", errors.New("prompt interrupted")
+	case <-c.Context.Done():
+		_, _ = fmt.Fprintln(c.App.Writer)
+		return "", c.Context.Err()
+	}
+}
+
 // endregion
 
 // region Factory
 
-func consumerFactory(queueType, endpoint string) (messaging.Consumer, error) {
+func consumerFactory(queueType, endpoint string, prefetch int, exclusive bool) (messaging.Consumer, error) {
+	var consumer *rabbitmq.Consumer
+	var err error
 	switch queueType {
 	case amqp091.QueueTypeClassic, amqp091.QueueTypeQuorum:
-		return rabbitmq.NewSimpleConsumer(endpoint)
+		consumer, err = rabbitmq.NewSimpleConsumer(endpoint)
+		if err == nil {
+			consumer.Exclusive(exclusive)
+		}
 	case amqp091.QueueTypeStream:
-		return rabbitmq.NewSimpleConsumer(endpoint, "first")
+		if exclusive {
+			return nil, fmt.Errorf("%v queue type does not support exclusive consumers", amqp091.QueueTypeStream)
+		}
+		consumer, err = rabbitmq.NewSimpleConsumer(endpoint, "first")
 	default:
 		return nil, errors.New("unsupported queue type: " + queueType)
 	}
+	if err != nil {
+		return nil, err
+	}
+	// source messages are acknowledged only after their publishings are confirmed, so the window of unconfirmed messages must be prefetched
+	if prefetch > 1 {
+		if err = consumer.Prefetch(prefetch); err != nil {
+			return nil, err
+		}
+	}
+	return consumer, nil
 }
 
-func managerFactory(queueType string, consumer messaging.Consumer, publisher messaging.Publisher, handler handlers.MessageHandler, selector selectors.Selector, tempQueue string) (managers.Manager, error) {
+func managerFactory(queueType string, consumer messaging.Consumer, publisher messaging.Publisher, handler handlers.MessageHandler, selector selectors.Selector, tempQueue string, opJournal *journal.Journal, counter messaging.MessageCounter, idleTimeout time.Duration, maxPriority int, stop <-chan struct{}) (managers.Manager, error) {
 	switch queueType {
 	case amqp091.QueueTypeClassic, amqp091.QueueTypeQuorum:
-		return managers.NewQueueManager(consumer, log, handler, publisher, selector, tempQueue), nil
+		return managers.NewQueueManager(consumer, log, handler, publisher, selector, tempQueue).
+			WithJournal(opJournal).
+			WithMessageCounter(counter).
+			WithIdleTimeout(idleTimeout).
+			WithMaxPriority(maxPriority).
+			WithStop(stop), nil
 	case amqp091.QueueTypeStream:
-		return managers.NewStreamManager(consumer, log, handler, publisher, selector), nil
+		return managers.NewStreamManager(consumer, log, handler, publisher, selector).
+			WithMessageCounter(counter).
+			WithIdleTimeout(idleTimeout).
+			WithStop(stop), nil
 	default:
 		return nil, errors.New("unsupported queue type: " + queueType)
 	}
@@ -175,7 +667,41 @@ func buildRabbitMQClient(endpoint, httpAPIEndpoint string) (*rabbitmq.Client, er
 	return rabbitmq.NewClient(httpAPIEndpoint, url.User.Username(), password)
 }
 
-func buildPublisher(endpoint string) (messaging.Publisher, error) {
+// buildSelector builds the selector of messages matching the filter and selected by the plugin of the operation.
+// All messages are selected if there is neither a filter nor a plugin select function.
+func buildSelector(c *cli.Context, op journal.Operation) (selectors.Selector, error) {
+	var all []selectors.Selector
+	if op.Filter != "" {
+		selector, err := selectors.NewFilterExprSelector(op.Filter)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, selector)
+	}
+	if op.Plugin != "" {
+		plugin := util.GetPlugin(c)
+		if plugin == nil {
+			return nil, fmt.Errorf("plugin %v is not loaded", op.Plugin)
+		}
+		if plugin.Selects() {
+			all = append(all, plugin)
+		}
+	}
+
+	switch len(all) {
+	case 0:
+		return selectors.NewYesSelector(), nil
+	case 1:
+		return all[0], nil
+	default:
+		return selectors.NewAllSelector(all...), nil
+	}
+}
+
+func buildPublisher(endpoint string, confirmWindow int) (messaging.Publisher, error) {
+	if confirmWindow > 1 {
+		return rabbitmq.NewPipelinedPublisher(endpoint, confirmWindow)
+	}
 	return rabbitmq.NewSimplePublisher(endpoint)
 }
 
@@ -6,6 +6,7 @@ import (
 	"github.com/urfave/cli/v2"
 
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/plugins"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/rabbitmq"
 )
 
@@ -14,6 +15,8 @@ type ctxKey string
 const (
 	clientKey    ctxKey = "rabbitmq-client"
 	publisherKey ctxKey = "rabbitmq-publisher"
+	stopKey      ctxKey = "stop"
+	pluginKey    ctxKey = "plugin"
 )
 
 func GetClient(ctx *cli.Context) *rabbitmq.Client {
@@ -39,3 +42,29 @@ func GetPublisher(ctx *cli.Context) messaging.Publisher {
 func AttachPublisher(ctx *cli.Context, publisher messaging.Publisher) {
 	ctx.Context = context.WithValue(ctx.Context, publisherKey, publisher)
 }
+
+// GetStop returns the channel which is closed when the user asks to stop the operation gracefully, nil if there is none.
+func GetStop(ctx *cli.Context) <-chan struct{} {
+	stop := ctx.Context.Value(stopKey)
+	if stop == nil {
+		return nil
+	}
+	return stop.(<-chan struct{})
+}
+
+func WithStop(ctx context.Context, stop <-chan struct{}) context.Context {
+	return context.WithValue(ctx, stopKey, stop)
+}
+
+// GetPlugin returns the loaded plugin, nil if there is none.
+func GetPlugin(ctx *cli.Context) *plugins.Plugin {
+	plugin := ctx.Context.Value(pluginKey)
+	if plugin == nil {
+		return nil
+	}
+	return plugin.(*plugins.Plugin)
+}
+
+func AttachPlugin(ctx *cli.Context, plugin *plugins.Plugin) {
+	ctx.Context = context.WithValue(ctx.Context, pluginKey, plugin)
+}
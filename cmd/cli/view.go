@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"log/slog"
 	"math"
 	"os"
@@ -25,6 +26,11 @@ Example: rabbitmq-cli -q <srcQueueName> -f 'type == "<some.msg.type>"' view -c 5
 Please keep in mind that all messages will be processed and --count only determines up to how many messages will be printed to stdout/file.
 If --count parameter is set to <= 0, all messages will be viewed. Default value is 0.`,
 			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: `Output format of viewed messages (json, table). Table shows messages in the effective delivery order (priority queues deliver messages by priority) together with their priority.`,
+				Value: handlers.ViewFormatJSON,
+			},
 			&cli.StringFlag{
 				Name:    "output",
 				Aliases: []string{"o"},
@@ -34,6 +40,10 @@ If --count parameter is set to <= 0, all messages will be viewed. Default value
 		Action: func(c *cli.Context) error {
 			count := c.Int("count")
 			output := c.String("output")
+			format := c.String("format")
+			if format != handlers.ViewFormatJSON && format != handlers.ViewFormatTable {
+				return fmt.Errorf("unsupported format %v. Supported formats: %v, %v", format, handlers.ViewFormatJSON, handlers.ViewFormatTable)
+			}
 
 			if count <= 0 {
 				count = math.MaxInt
@@ -55,7 +65,7 @@ If --count parameter is set to <= 0, all messages will be viewed. Default value
 				}()
 			}
 
-			return manageQueue(c, handlers.NewViewHandler(count, outputFile))
+			return manageQueue(c, handlers.NewViewHandler(count, outputFile).WithFormat(format))
 		},
 	}
 }
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/happening-oss/rabbitmq-message-ops/cmd/cli/util"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+)
+
+const (
+	webhookDefaultTimeout      = 10 * time.Second
+	webhookDefaultRetries      = 3
+	webhookDefaultRetryBackoff = time.Second
+
+	// webhookEnvPrefix marks header values read from an environment variable, e.g. "Authorization: env:TOKEN".
+	webhookEnvPrefix = "env:"
+	// webhookRedactedValue replaces literal values of sensitive headers in the operation journal.
+	webhookRedactedValue = "<redacted>"
+)
+
+// webhookSensitiveHeaders are (parts of) names of headers whose literal values are not stored in the operation journal.
+var webhookSensitiveHeaders = []string{"authorization", "cookie", "token", "secret", "password", "api-key", "apikey"}
+
+func webhookMessages() *cli.Command {
+	return &cli.Command{
+		Name:  "webhook",
+		Usage: "Post messages to an HTTP service",
+		Description: `Posts each selected message to the URL, one at a time in queue order, and handles it as decided by the response:
+  2xx                     the message is removed, unless the response is a JSON verdict of exec command, which is applied instead
+  408, 429, 5xx, errors   the request is retried with exponential backoff, the operation fails once all retries fail
+  401, 403, 404, 405      the operation fails, since the URL or the headers are wrong
+  other                   the message is kept, the number of kept messages per status is printed at the end
+Messages are posted as JSON documents (envelope format, like in edit command) or as raw bodies with properties and headers mapped to X-Amqp-* HTTP headers (raw format).`,
+		UsageText: `rabbitmq-cli webhook [command options]
+Example: rabbitmq-cli -q <srcQueueName> -f 'type == "<some.msg.type>"' webhook --url https://tickets.internal/dlq -H "Authorization: env:DLQ_AUTHORIZATION"`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "url",
+				Usage:    "URL messages are posted to.",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Format of posted messages (envelope, raw).",
+				Value: handlers.WebhookFormatEnvelope,
+			},
+			&cli.StringSliceFlag{
+				Name:    "header",
+				Aliases: []string{"H"},
+				Usage:   `Header added to each request ("Key: Value"), can be repeated. Headers are stored in the operation journal.`,
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Timeout of a single request.",
+				Value: webhookDefaultTimeout,
+			},
+			&cli.IntFlag{
+				Name:  "retries",
+				Usage: "Number of retries of a failed request.",
+				Value: webhookDefaultRetries,
+			},
+			&cli.DurationFlag{
+				Name:  "retry-backoff",
+				Usage: "Backoff before the first retry, doubled after each retry.",
+				Value: webhookDefaultRetryBackoff,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			handler, err := webhookHandler(c, commandArgs(c))
+			if err != nil {
+				return err
+			}
+			err = manageQueue(c, handler)
+			printKept(c, handler.Kept())
+			return err
+		},
+	}
+}
+
+// region Helpers
+
+// webhookHandler builds the webhook handler from the (journaled) command arguments.
+func webhookHandler(c *cli.Context, args map[string]string) (*handlers.WebhookHandler, error) {
+	format := args["format"]
+	if format == "" {
+		format = handlers.WebhookFormatEnvelope
+	}
+	if format != handlers.WebhookFormatEnvelope && format != handlers.WebhookFormatRaw {
+		return nil, fmt.Errorf("unsupported webhook format %q", format)
+	}
+	timeout, err := durationArg(args, "timeout", webhookDefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	backoff, err := durationArg(args, "retry-backoff", webhookDefaultRetryBackoff)
+	if err != nil {
+		return nil, err
+	}
+	retries := webhookDefaultRetries
+	if args["retries"] != "" {
+		if retries, err = strconv.Atoi(args["retries"]); err != nil || retries < 0 {
+			return nil, fmt.Errorf("invalid webhook retries %q", args["retries"])
+		}
+	}
+
+	handler := handlers.NewWebhookHandler(util.GetPublisher(c), args["url"]).
+		WithContext(c.Context).
+		WithFormat(format).
+		WithTimeout(timeout).
+		WithRetries(retries, backoff).
+		WithQueueCheck(func(queue string) error {
+			_, err := util.GetClient(c).GetQueueInfo(queue)
+			return err
+		})
+
+	headers, err := argValues(args, "header")
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range headers {
+		key, value, err := headerValue(header)
+		if err != nil {
+			return nil, err
+		}
+		handler.WithHeader(key, value)
+	}
+	return handler, nil
+}
+
+// printKept prints the number of messages kept by each response status of the webhook.
+func printKept(c *cli.Context, kept map[int]int) {
+	statuses := make([]int, 0, len(kept))
+	for status := range kept {
+		statuses = append(statuses, status)
+	}
+	slices.Sort(statuses)
+	for _, status := range statuses {
+		_, _ = fmt.Fprintf(c.App.Writer, "Kept %v messages on response %v %v.\n", kept[status], status, http.StatusText(status))
+	}
+}
+
+// headerValue parses the "Key: Value" header, reading "env:<VAR>" values from the environment.
+func headerValue(header string) (string, string, error) {
+	key, value, ok := strings.Cut(header, ":")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !ok || key == "" {
+		return "", "", fmt.Errorf(`invalid webhook header %q, expected "Key: Value"`, header)
+	}
+	if value == webhookRedactedValue {
+		return "", "", fmt.Errorf(`value of webhook header %q was redacted in the operation journal. Please pass it as "%v: env:<VAR>" to be able to resume`, key, key)
+	}
+	if name, ok := strings.CutPrefix(value, webhookEnvPrefix); ok {
+		if value, ok = os.LookupEnv(name); !ok {
+			return "", "", fmt.Errorf("environment variable %v of webhook header %q is not set", name, key)
+		}
+	}
+	return key, value, nil
+}
+
+// redactHeaders replaces literal values of sensitive headers, environment variable references are kept.
+func redactHeaders(headers []string) []string {
+	redacted := make([]string, 0, len(headers))
+	for _, header := range headers {
+		key, value, _ := strings.Cut(header, ":")
+		value = strings.TrimSpace(value)
+		if sensitiveHeader(key) && !strings.HasPrefix(value, webhookEnvPrefix) {
+			header = strings.TrimSpace(key) + ": " + webhookRedactedValue
+		}
+		redacted = append(redacted, header)
+	}
+	return redacted
+}
+
+func sensitiveHeader(key string) bool {
+	key = strings.ToLower(strings.TrimSpace(key))
+	for _, sensitive := range webhookSensitiveHeaders {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// endregion
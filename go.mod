@@ -9,7 +9,10 @@ require (
 	github.com/onsi/gomega v1.33.0
 	github.com/rabbitmq/amqp091-go v1.8.1
 	github.com/stretchr/testify v1.8.4
+	github.com/tetratelabs/wazero v1.8.2
 	github.com/urfave/cli/v2 v2.27.2
+	golang.org/x/term v0.19.0
+	golang.org/x/time v0.5.0
 )
 
 require (
@@ -0,0 +1,26 @@
+package handlers
+
+import "sync"
+
+// queueCheck checks each queue messages are published to once, e.g. if the queue exists.
+type queueCheck struct {
+	check   func(queue string) error
+	checked map[string]bool
+	mu      sync.Mutex
+}
+
+func (c *queueCheck) do(queue string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.check == nil || c.checked[queue] {
+		return nil
+	}
+	if err := c.check(queue); err != nil {
+		return err
+	}
+	if c.checked == nil {
+		c.checked = make(map[string]bool)
+	}
+	c.checked[queue] = true
+	return nil
+}
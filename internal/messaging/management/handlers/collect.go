@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// CollectHandler keeps up to limit messages in memory, e.g. for browsing. All messages are kept in the queue.
+type CollectHandler struct {
+	limit    int
+	messages []amqp091.Delivery
+}
+
+func NewCollectHandler(limit int) *CollectHandler {
+	return &CollectHandler{limit: limit}
+}
+
+func (h *CollectHandler) Handle(msg amqp091.Delivery) (bool, error) {
+	if len(h.messages) < h.limit {
+		h.messages = append(h.messages, msg)
+	}
+	return true, nil
+}
+
+// Messages returns the collected messages in the order they were handled.
+func (h *CollectHandler) Messages() []amqp091.Delivery {
+	return h.messages
+}
@@ -0,0 +1,22 @@
+package handlers_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+)
+
+var _ = Describe("Collect handler", func() {
+	It("keeps all messages and collects up to the limit", func() {
+		handler := handlers.NewCollectHandler(2)
+		for _, id := range []string{"msg-1", "msg-2", "msg-3"} {
+			requeue, err := handler.Handle(amqp091.Delivery{MessageId: id})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requeue).To(BeTrue())
+		}
+		Expect(handler.Messages()).To(HaveLen(2))
+		Expect(handler.Messages()[1].MessageId).To(Equal("msg-2"))
+	})
+})
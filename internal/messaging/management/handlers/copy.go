@@ -1,22 +1,119 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
 	"github.com/rabbitmq/amqp091-go"
 
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
 )
 
+// CopyHandler copies each message to all destination queues and to the queue computed by the destination expression.
+// The source message is acknowledged only once all its copies are confirmed, so a message is handled only if it reached all destinations.
+// If copying to one of the destinations fails, the copies already published to the other destinations are not withdrawn,
+// so copying the message again (e.g. on resume) duplicates it in those destinations.
+// Copies are counted once they are confirmed (see HandleConfirmed).
 type CopyHandler struct {
-	publisher messaging.Publisher
-	destQueue string
+	publisher       messaging.Publisher
+	destQueues      []string
+	destinationExpr *selectors.KeyExpr
+	queues          queueCheck
+	counts          map[string]int
+	mu              sync.Mutex
+}
+
+func NewCopyHandler(publisher messaging.Publisher, destQueues ...string) *CopyHandler {
+	counts := make(map[string]int, len(destQueues))
+	for _, destQueue := range destQueues {
+		counts[destQueue] = 0
+	}
+	return &CopyHandler{publisher: publisher, destQueues: destQueues, counts: counts}
+}
+
+// WithDestinationExpr sets the expression computing an additional destination queue of each message.
+func (h *CopyHandler) WithDestinationExpr(destinationExpr *selectors.KeyExpr) *CopyHandler {
+	h.destinationExpr = destinationExpr
+	return h
 }
 
-func NewCopyHandler(publisher messaging.Publisher, destQueue string) *CopyHandler {
-	return &CopyHandler{publisher: publisher, destQueue: destQueue}
+// WithQueueCheck sets the check of the queues computed by the destination expression, e.g. if the queue exists. Each queue is checked once.
+func (h *CopyHandler) WithQueueCheck(checkQueue func(queue string) error) *CopyHandler {
+	h.queues.check = checkQueue
+	return h
 }
 
+// Handle copies the message and counts its copies right away.
 func (h *CopyHandler) Handle(msg amqp091.Delivery) (bool, error) {
-	// copy/publish message to the destination queue
-	return true, h.publisher.Publish(h.destQueue, mappers.DeliveryPublishing(msg))
+	requeue, confirmed, err := h.HandleConfirmed(msg)
+	if err != nil {
+		return requeue, err
+	}
+	confirmed()
+	return requeue, nil
+}
+
+// HandleConfirmed copies the message. Its copies are counted once confirmed is called.
+func (h *CopyHandler) HandleConfirmed(msg amqp091.Delivery) (bool, func(), error) {
+	destQueues, err := h.destinations(msg)
+	if err != nil {
+		return true, nil, err
+	}
+
+	// copy/publish message to the destination queues
+	publishing := mappers.DeliveryPublishing(msg)
+	for _, destQueue := range destQueues {
+		if err = h.publisher.Publish(destQueue, publishing); err != nil {
+			return true, nil, err
+		}
+	}
+	return true, func() { h.count(destQueues) }, nil
 }
+
+// Counts returns the number of confirmed copies in each destination queue.
+func (h *CopyHandler) Counts() map[string]int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	counts := make(map[string]int, len(h.counts))
+	for destQueue, count := range h.counts {
+		counts[destQueue] = count
+	}
+	return counts
+}
+
+// region Private
+
+func (h *CopyHandler) count(destQueues []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, destQueue := range destQueues {
+		h.counts[destQueue]++
+	}
+}
+
+// destinations returns the destination queues of the message, each queue once.
+func (h *CopyHandler) destinations(msg amqp091.Delivery) ([]string, error) {
+	if h.destinationExpr == nil {
+		return h.destQueues, nil
+	}
+	destQueue, err := h.destinationExpr.Key(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute destination queue: %w", err)
+	}
+	if destQueue == "" {
+		return nil, errors.New("destination expression returned empty queue name")
+	}
+	if slices.Contains(h.destQueues, destQueue) {
+		return h.destQueues, nil
+	}
+	if err = h.queues.do(destQueue); err != nil {
+		return nil, err
+	}
+	return append(slices.Clip(h.destQueues), destQueue), nil
+}
+
+// endregion
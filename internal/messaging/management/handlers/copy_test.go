@@ -12,6 +12,7 @@ import (
 	"github.com/happening-oss/rabbitmq-message-ops/internal/tests/util"
 
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
 )
 
 var _ = Describe("Copy handler", func() {
@@ -54,4 +55,85 @@ var _ = Describe("Copy handler", func() {
 			})
 		})
 	})
+
+	Describe("copying message to multiple destinations", func() {
+
+		It("publishes message to all destinations and counts copies per destination", func() {
+			handler := handlers.NewCopyHandler(pubMock, "destQueue1", "destQueue2")
+			pubMock.On(util.NameOf(pubMock.Publish), "destQueue1", mock.Anything).Return(nil).Twice()
+			pubMock.On(util.NameOf(pubMock.Publish), "destQueue2", mock.Anything).Return(nil).Twice()
+
+			for i := 0; i < 2; i++ {
+				requeue, err := handler.Handle(amqp091.Delivery{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(requeue).To(BeTrue())
+			}
+			Expect(handler.Counts()).To(Equal(map[string]int{"destQueue1": 2, "destQueue2": 2}))
+		})
+
+		It("publishes message to destination computed by expression", func() {
+			destinationExpr, err := selectors.NewKeyExpr(`"debug." + headers.tenant`)
+			Expect(err).ToNot(HaveOccurred())
+			var checked []string
+			handler := handlers.NewCopyHandler(pubMock, "destQueue").
+				WithDestinationExpr(destinationExpr).
+				WithQueueCheck(func(queue string) error {
+					checked = append(checked, queue)
+					return nil
+				})
+			pubMock.On(util.NameOf(pubMock.Publish), "destQueue", mock.Anything).Return(nil).Times(3)
+			pubMock.On(util.NameOf(pubMock.Publish), "debug.acme", mock.Anything).Return(nil).Twice()
+			pubMock.On(util.NameOf(pubMock.Publish), "debug.other", mock.Anything).Return(nil).Once()
+
+			for _, tenant := range []string{"acme", "other", "acme"} {
+				_, err = handler.Handle(amqp091.Delivery{Headers: amqp091.Table{"tenant": tenant}})
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(checked).To(Equal([]string{"debug.acme", "debug.other"}))
+			Expect(handler.Counts()).To(Equal(map[string]int{"destQueue": 3, "debug.acme": 2, "debug.other": 1}))
+		})
+
+		It("publishes message once if computed destination is also a fixed destination", func() {
+			destinationExpr, err := selectors.NewKeyExpr(`headers.queue`)
+			Expect(err).ToNot(HaveOccurred())
+			handler := handlers.NewCopyHandler(pubMock, "destQueue").WithDestinationExpr(destinationExpr)
+			pubMock.On(util.NameOf(pubMock.Publish), "destQueue", mock.Anything).Return(nil).Once()
+
+			_, err = handler.Handle(amqp091.Delivery{Headers: amqp091.Table{"queue": "destQueue"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.Counts()).To(Equal(map[string]int{"destQueue": 1}))
+		})
+
+		It("does not count message which was not published to all destinations", func() {
+			handler := handlers.NewCopyHandler(pubMock, "destQueue1", "destQueue2")
+			pubMock.On(util.NameOf(pubMock.Publish), "destQueue1", mock.Anything).Return(nil).Once()
+			pubMock.On(util.NameOf(pubMock.Publish), "destQueue2", mock.Anything).Return(errors.New("")).Once()
+
+			_, err := handler.Handle(amqp091.Delivery{})
+			Expect(err).To(HaveOccurred())
+			Expect(handler.Counts()).To(Equal(map[string]int{"destQueue1": 0, "destQueue2": 0}))
+		})
+
+		It("counts copies once they are confirmed", func() {
+			handler := handlers.NewCopyHandler(pubMock, "destQueue1", "destQueue2")
+			pubMock.On(util.NameOf(pubMock.Publish), mock.Anything, mock.Anything).Return(nil).Twice()
+
+			requeue, confirmed, err := handler.HandleConfirmed(amqp091.Delivery{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requeue).To(BeTrue())
+			Expect(handler.Counts()).To(Equal(map[string]int{"destQueue1": 0, "destQueue2": 0}))
+
+			confirmed()
+			Expect(handler.Counts()).To(Equal(map[string]int{"destQueue1": 1, "destQueue2": 1}))
+		})
+
+		It("returns error if destination cannot be computed", func() {
+			destinationExpr, err := selectors.NewKeyExpr(`headers.tenant`)
+			Expect(err).ToNot(HaveOccurred())
+			handler := handlers.NewCopyHandler(pubMock).WithDestinationExpr(destinationExpr)
+
+			_, err = handler.Handle(amqp091.Delivery{})
+			Expect(err).To(MatchError(ContainSubstring("failed to compute destination queue")))
+		})
+	})
 })
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
+)
+
+// EditHandler lets the user edit each message. Messages are always kept, edited ones in place of the originals.
+type EditHandler struct {
+	editor Editor
+}
+
+func NewEditHandler(editor Editor) *EditHandler {
+	return &EditHandler{editor: editor}
+}
+
+func (h *EditHandler) Handle(msg amqp091.Delivery) (bool, error) {
+	_, _, err := h.HandleEdited(msg)
+	return true, err
+}
+
+func (h *EditHandler) HandleEdited(msg amqp091.Delivery) (bool, *amqp091.Publishing, error) {
+	publishing := mappers.DeliveryPublishing(msg)
+	edited, changed, err := h.editor(publishing)
+	if err != nil || !changed {
+		return true, nil, err
+	}
+	return true, &edited, nil
+}
@@ -0,0 +1,47 @@
+package handlers_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+)
+
+var _ = Describe("Edit handler", func() {
+	It("keeps the edited message", func() {
+		handler := handlers.NewEditHandler(func(msg amqp091.Publishing) (amqp091.Publishing, bool, error) {
+			msg.Body = []byte("fixed")
+			return msg, true, nil
+		})
+
+		requeue, edited, err := handler.HandleEdited(amqp091.Delivery{MessageId: "msg-1", Body: []byte("typo")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeTrue())
+		Expect(edited.MessageId).To(Equal("msg-1"))
+		Expect(edited.Body).To(Equal([]byte("fixed")))
+	})
+
+	It("keeps the original message if it was not changed", func() {
+		handler := handlers.NewEditHandler(func(msg amqp091.Publishing) (amqp091.Publishing, bool, error) {
+			return msg, false, nil
+		})
+
+		requeue, edited, err := handler.HandleEdited(amqp091.Delivery{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeTrue())
+		Expect(edited).To(BeNil())
+	})
+
+	It("returns editor error", func() {
+		handler := handlers.NewEditHandler(func(msg amqp091.Publishing) (amqp091.Publishing, bool, error) {
+			return msg, false, errors.New("invalid message document")
+		})
+
+		requeue, err := handler.Handle(amqp091.Delivery{})
+		Expect(err).To(MatchError("invalid message document"))
+		Expect(requeue).To(BeTrue())
+	})
+})
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
+)
+
+// maxVerdictSize is the maximal length of a verdict line, which may contain a replacement message.
+const maxVerdictSize = 64 * 1024 * 1024
+
+// ExecHandler sends each message to an external process and applies the verdict returned by the process.
+// Messages are written as single line JSON documents (see mappers.PublishingDocument) and the process answers each one with a single line verdict.
+type ExecHandler struct {
+	*VerdictHandler
+	requests io.Writer
+	verdicts *bufio.Scanner
+}
+
+func NewExecHandler(publisher messaging.Publisher, requests io.Writer, verdicts io.Reader) *ExecHandler {
+	scanner := bufio.NewScanner(verdicts)
+	scanner.Buffer(nil, maxVerdictSize)
+	h := &ExecHandler{requests: requests, verdicts: scanner}
+	h.VerdictHandler = NewVerdictHandler(publisher, h.exchange)
+	return h
+}
+
+// WithQueueCheck sets the check of the queues messages are moved to, e.g. if the queue exists. Each queue is checked once.
+func (h *ExecHandler) WithQueueCheck(checkQueue func(queue string) error) *ExecHandler {
+	h.VerdictHandler.WithQueueCheck(checkQueue)
+	return h
+}
+
+// region Private
+
+// exchange writes the message to the process and reads its verdict.
+func (h *ExecHandler) exchange(msg amqp091.Delivery) (Verdict, error) {
+	doc, err := mappers.PublishingDocument(mappers.DeliveryPublishing(msg))
+	if err != nil {
+		return Verdict{}, err
+	}
+	var line bytes.Buffer
+	if err = json.Compact(&line, doc); err != nil {
+		return Verdict{}, err
+	}
+	line.WriteByte('\n')
+	if _, err = h.requests.Write(line.Bytes()); err != nil {
+		return Verdict{}, fmt.Errorf("exec: failed to send message: %w", err)
+	}
+
+	if !h.verdicts.Scan() {
+		if err = h.verdicts.Err(); err != nil {
+			return Verdict{}, fmt.Errorf("exec: failed to read verdict: %w", err)
+		}
+		return Verdict{}, errors.New("exec: process closed its output before returning the verdict")
+	}
+	var verdict Verdict
+	if err = json.Unmarshal(h.verdicts.Bytes(), &verdict); err != nil {
+		return Verdict{}, fmt.Errorf("exec: invalid verdict: %w", err)
+	}
+	return verdict, nil
+}
+
+// endregion
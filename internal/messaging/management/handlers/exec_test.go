@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/mock"
+
+	mmocks "github.com/happening-oss/rabbitmq-message-ops/internal/messaging/mocks"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/tests/util"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
+)
+
+var _ = Describe("Exec handler", func() {
+	var pubMock *mmocks.Publisher
+	var handler *handlers.ExecHandler
+
+	// process answers each message with the verdict chosen by its message ID
+	startProcess := func(verdicts map[string]string) {
+		requestsReader, requestsWriter := io.Pipe()
+		verdictsReader, verdictsWriter := io.Pipe()
+		go func() {
+			defer GinkgoRecover()
+			defer verdictsWriter.Close()
+			scanner := bufio.NewScanner(requestsReader)
+			for scanner.Scan() {
+				var doc mappers.Document
+				Expect(json.Unmarshal(scanner.Bytes(), &doc)).To(Succeed())
+				verdict, ok := verdicts[doc.MessageID]
+				if !ok {
+					return
+				}
+				_, _ = verdictsWriter.Write([]byte(verdict + "\n"))
+			}
+		}()
+		DeferCleanup(requestsWriter.Close)
+		handler = handlers.NewExecHandler(pubMock, requestsWriter, verdictsReader)
+	}
+
+	BeforeEach(func() {
+		pubMock = mmocks.NewPublisher(GinkgoT())
+	})
+
+	It("applies verdicts of the process", func() {
+		startProcess(map[string]string{
+			"msg-keep":    `{"verdict":"keep"}`,
+			"msg-drop":    `{"verdict":"drop"}`,
+			"msg-move":    `{"verdict":"move","queue":"destQueue"}`,
+			"msg-replace": `{"verdict":"replace","message":{"messageID":"msg-fixed","bodyFormat":"text","body":"fixed"}}`,
+		})
+		pubMock.On(util.NameOf(pubMock.Publish), "destQueue", mock.MatchedBy(func(msg amqp091.Publishing) bool {
+			return msg.MessageId == "msg-move"
+		})).Return(nil).Once()
+		checked := 0
+		handler.WithQueueCheck(func(queue string) error {
+			checked++
+			return nil
+		})
+
+		requeue, edited, err := handler.HandleEdited(amqp091.Delivery{MessageId: "msg-keep"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeTrue())
+		Expect(edited).To(BeNil())
+
+		requeue, err = handler.Handle(amqp091.Delivery{MessageId: "msg-drop"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeFalse())
+
+		requeue, err = handler.Handle(amqp091.Delivery{MessageId: "msg-move"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeFalse())
+		Expect(checked).To(Equal(1))
+
+		requeue, edited, err = handler.HandleEdited(amqp091.Delivery{MessageId: "msg-replace", Body: []byte("typo")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeTrue())
+		Expect(edited.MessageId).To(Equal("msg-fixed"))
+		Expect(edited.Body).To(Equal([]byte("fixed")))
+	})
+
+	It("does not move message to a queue which failed the check", func() {
+		startProcess(map[string]string{"msg-1": `{"verdict":"move","queue":"missingQueue"}`})
+		handler.WithQueueCheck(func(queue string) error { return errors.New("queue not found") })
+
+		requeue, err := handler.Handle(amqp091.Delivery{MessageId: "msg-1"})
+		Expect(err).To(MatchError("queue not found"))
+		Expect(requeue).To(BeTrue())
+	})
+
+	It("returns error for invalid verdict", func() {
+		startProcess(map[string]string{"msg-1": `{"verdict":"unknown"}`})
+
+		requeue, err := handler.Handle(amqp091.Delivery{MessageId: "msg-1"})
+		Expect(err).To(MatchError(`unsupported verdict "unknown"`))
+		Expect(requeue).To(BeTrue())
+	})
+
+	It("returns error once the process closes its output", func() {
+		startProcess(map[string]string{})
+
+		requeue, err := handler.Handle(amqp091.Delivery{MessageId: "msg-1"})
+		Expect(err).To(MatchError("exec: process closed its output before returning the verdict"))
+		Expect(requeue).To(BeTrue())
+	})
+})
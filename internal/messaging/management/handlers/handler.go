@@ -9,3 +9,18 @@ import (
 type MessageHandler interface {
 	Handle(msg amqp091.Delivery) (requeue bool, err error)
 }
+
+// EditingHandler is implemented by handlers which may replace kept messages with edited ones.
+type EditingHandler interface {
+	MessageHandler
+	// HandleEdited handles the message like Handle. If the message is kept and edited is not nil, the edited message is kept in its place.
+	HandleEdited(msg amqp091.Delivery) (requeue bool, edited *amqp091.Publishing, err error)
+}
+
+// ConfirmingHandler is implemented by handlers which record the effects of handling a message (e.g. counts of copies)
+// only once the messages published while handling it are confirmed.
+type ConfirmingHandler interface {
+	MessageHandler
+	// HandleConfirmed handles the message like Handle. Confirmed is called once the messages published while handling it are confirmed.
+	HandleConfirmed(msg amqp091.Delivery) (requeue bool, confirmed func(), err error)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
+)
+
+// Editor lets the user edit the message. changed is false if the message was left as it was.
+type Editor func(msg amqp091.Publishing) (edited amqp091.Publishing, changed bool, err error)
+
+// InteractiveHandler shows each message and lets the user decide whether it is kept, handled by the wrapped handler (act) or edited.
+// Once the user skips all or quits, the remaining messages are kept without asking.
+type InteractiveHandler struct {
+	handler MessageHandler
+	action  string
+	in      *bufio.Reader
+	out     io.Writer
+	editor  Editor
+	quit    func()
+
+	shown   int
+	skipAll bool
+}
+
+func NewInteractiveHandler(handler MessageHandler, action string, in io.Reader, out io.Writer) *InteractiveHandler {
+	return &InteractiveHandler{handler: handler, action: action, in: bufio.NewReader(in), out: out}
+}
+
+// WithEditor enables editing messages with the provided editor.
+func (h *InteractiveHandler) WithEditor(editor Editor) *InteractiveHandler {
+	h.editor = editor
+	return h
+}
+
+// WithQuit sets the function called once the user quits, e.g. to stop processing the remaining messages.
+func (h *InteractiveHandler) WithQuit(quit func()) *InteractiveHandler {
+	h.quit = quit
+	return h
+}
+
+func (h *InteractiveHandler) Handle(msg amqp091.Delivery) (bool, error) {
+	requeue, _, err := h.HandleEdited(msg)
+	return requeue, err
+}
+
+func (h *InteractiveHandler) HandleEdited(msg amqp091.Delivery) (bool, *amqp091.Publishing, error) {
+	if h.skipAll {
+		return true, nil, nil
+	}
+	h.shown++
+
+	var edited *amqp091.Publishing
+	current := msg
+	for {
+		if err := h.show(current); err != nil {
+			return true, nil, err
+		}
+		key, err := h.ask()
+		if err != nil {
+			return true, nil, err
+		}
+
+		switch key {
+		case "k", "keep":
+			return true, edited, nil
+		case "a", "act":
+			// edited message is handled in place of the original one
+			requeue, err := h.handler.Handle(current)
+			if requeue {
+				return true, edited, err
+			}
+			return false, nil, err
+		case "e", "edit":
+			if h.editor == nil {
+				h.printf("Editing is not supported.\n")
+				continue
+			}
+			publishing, changed, err := h.editor(mappers.DeliveryPublishing(current))
+			if err != nil {
+				// the message is shown again, so that the user can retry or choose another key
+				h.printf("Editing failed: %v\n", err)
+				continue
+			}
+			if changed {
+				edited = &publishing
+				current = mappers.PublishedDelivery(msg, publishing)
+			}
+		case "s", "skip-all":
+			h.skipAll = true
+			return true, edited, nil
+		case "q", "quit":
+			h.skipAll = true
+			if h.quit != nil {
+				h.quit()
+			}
+			return true, edited, nil
+		default:
+			h.printf("Unknown key %q.\n", key)
+		}
+	}
+}
+
+// region Helpers
+
+func (h *InteractiveHandler) show(msg amqp091.Delivery) error {
+	data, err := mappers.PrettyDelivery(msg)
+	if err != nil {
+		return err
+	}
+	h.printf("\nMessage #%v:\n%s\n", h.shown, data)
+	return nil
+}
+
+// ask reads the key chosen by the user. Closed input is treated as quit, so that no message is handled without a decision.
+func (h *InteractiveHandler) ask() (string, error) {
+	h.printf("[k]eep, [a]ct (%v), [e]dit, [s]kip all, [q]uit: ", h.action)
+	answer, err := h.in.ReadString('\n')
+	if errors.Is(err, io.EOF) && answer == "" {
+		h.printf("\n")
+		return "q", nil
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.ToLower(strings.TrimSpace(answer)), nil
+}
+
+func (h *InteractiveHandler) printf(format string, args ...any) {
+	_, _ = fmt.Fprintf(h.out, format, args...)
+}
+
+// endregion
@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+)
+
+var _ = Describe("Interactive handler", func() {
+	var output *bytes.Buffer
+	var quitCalled bool
+
+	newHandler := func(input string) *handlers.InteractiveHandler {
+		output = &bytes.Buffer{}
+		quitCalled = false
+		return handlers.NewInteractiveHandler(handlers.NewPurgeHandler(), "purge", strings.NewReader(input), output).
+			WithEditor(func(msg amqp091.Publishing) (amqp091.Publishing, bool, error) {
+				msg.Body = []byte(`{"edited":true}`)
+				return msg, true, nil
+			}).
+			WithQuit(func() { quitCalled = true })
+	}
+
+	It("keeps or acts on messages by the chosen key", func() {
+		handler := newHandler("k\nx\na\n")
+
+		requeue, err := handler.Handle(amqp091.Delivery{MessageId: "msg-1", Body: []byte(`{"key":"value"}`)})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeTrue())
+		Expect(output.String()).To(ContainSubstring(`"key": "value"`))
+
+		requeue, err = handler.Handle(amqp091.Delivery{MessageId: "msg-2"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeFalse())
+		Expect(output.String()).To(ContainSubstring(`Unknown key "x"`))
+	})
+
+	It("keeps the edited message", func() {
+		handler := newHandler("e\nk\n")
+
+		requeue, edited, err := handler.HandleEdited(amqp091.Delivery{MessageId: "msg-1", Body: []byte("body")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeTrue())
+		Expect(edited).ToNot(BeNil())
+		Expect(edited.MessageId).To(Equal("msg-1"))
+		Expect(edited.Body).To(Equal([]byte(`{"edited":true}`)))
+	})
+
+	It("keeps the remaining messages without asking after skipping all", func() {
+		handler := newHandler("s\n")
+
+		for i := 0; i < 2; i++ {
+			requeue, err := handler.Handle(amqp091.Delivery{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requeue).To(BeTrue())
+		}
+		Expect(quitCalled).To(BeFalse())
+	})
+
+	It("quits once the input is closed", func() {
+		handler := newHandler("")
+
+		requeue, err := handler.Handle(amqp091.Delivery{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeTrue())
+		Expect(quitCalled).To(BeTrue())
+	})
+})
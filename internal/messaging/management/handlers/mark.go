@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"slices"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+)
+
+// MarkHandler applies actions marked on messages at known positions in the queue, e.g. in the browser.
+// A message is acted on only if a pending mark at or before its position has the same identity,
+// so that a message which is no longer at the marked position is never acted on by mistake. Every message must be passed to the handler.
+type MarkHandler struct {
+	pending  []Mark
+	position int
+	applied  int
+}
+
+func NewMarkHandler(marks []Mark) *MarkHandler {
+	pending := slices.Clone(marks)
+	slices.SortFunc(pending, func(a, b Mark) int { return a.Position - b.Position })
+	return &MarkHandler{pending: pending}
+}
+
+func (h *MarkHandler) Handle(msg amqp091.Delivery) (bool, error) {
+	position := h.position
+	h.position++
+	for i, mark := range h.pending {
+		if mark.Position > position {
+			break
+		}
+		if mark.Identity.Matches(msg) {
+			h.pending = slices.Delete(h.pending, i, i+1)
+			h.applied++
+			return mark.Handler.Handle(msg)
+		}
+	}
+	return true, nil
+}
+
+// Applied returns the number of applied marks.
+func (h *MarkHandler) Applied() int {
+	return h.applied
+}
+
+// Pending returns the marks whose messages were not found.
+func (h *MarkHandler) Pending() []Mark {
+	return h.pending
+}
+
+// region Structs
+
+// Mark is an action marked on the message at the position in the queue.
+type Mark struct {
+	Position int
+	Identity *journal.MessageIdentity
+	Handler  MessageHandler
+}
+
+// endregion
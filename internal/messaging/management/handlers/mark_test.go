@@ -0,0 +1,53 @@
+package handlers_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+)
+
+var _ = Describe("Mark handler", func() {
+	messages := []amqp091.Delivery{
+		{MessageId: "msg-0", Body: []byte("0")},
+		{MessageId: "msg-1", Body: []byte("1")},
+		{MessageId: "msg-2", Body: []byte("2")},
+	}
+	mark := func(position int, msg amqp091.Delivery) handlers.Mark {
+		return handlers.Mark{Position: position, Identity: journal.IdentityFromDelivery(msg), Handler: handlers.NewPurgeHandler()}
+	}
+	handle := func(handler *handlers.MarkHandler, messages ...amqp091.Delivery) []bool {
+		var requeues []bool
+		for _, msg := range messages {
+			requeue, err := handler.Handle(msg)
+			Expect(err).ToNot(HaveOccurred())
+			requeues = append(requeues, requeue)
+		}
+		return requeues
+	}
+
+	It("acts on marked messages only", func() {
+		handler := handlers.NewMarkHandler([]handlers.Mark{mark(2, messages[2]), mark(0, messages[0])})
+
+		Expect(handle(handler, messages...)).To(Equal([]bool{false, true, false}))
+		Expect(handler.Applied()).To(Equal(2))
+		Expect(handler.Pending()).To(BeEmpty())
+	})
+
+	It("acts on marked message which moved behind its position", func() {
+		handler := handlers.NewMarkHandler([]handlers.Mark{mark(1, messages[1])})
+
+		Expect(handle(handler, messages[0], messages[2], messages[1])).To(Equal([]bool{true, true, false}))
+		Expect(handler.Applied()).To(Equal(1))
+	})
+
+	It("keeps marks of messages which are not found", func() {
+		handler := handlers.NewMarkHandler([]handlers.Mark{mark(1, messages[1])})
+
+		Expect(handle(handler, messages[0], messages[2])).To(Equal([]bool{true, true}))
+		Expect(handler.Applied()).To(BeZero())
+		Expect(handler.Pending()).To(HaveLen(1))
+	})
+})
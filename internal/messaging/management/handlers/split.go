@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
+)
+
+// SplitHandler moves messages to the destination queues (shards), round-robin or by consistent hash of the message key.
+// Messages with the same key are moved to the same shard in the order they are handled, so the order of each key is kept.
+// Moved messages are counted once they are confirmed (see HandleConfirmed).
+type SplitHandler struct {
+	publisher  messaging.Publisher
+	destQueues []string
+	keyExpr    *selectors.KeyExpr
+	next       int
+	counts     map[string]int
+	mu         sync.Mutex
+}
+
+func NewSplitHandler(publisher messaging.Publisher, destQueues ...string) *SplitHandler {
+	counts := make(map[string]int, len(destQueues))
+	for _, destQueue := range destQueues {
+		counts[destQueue] = 0
+	}
+	return &SplitHandler{publisher: publisher, destQueues: destQueues, counts: counts}
+}
+
+// WithKeyExpr distributes messages by consistent hash of the key computed by the expression instead of round-robin.
+func (h *SplitHandler) WithKeyExpr(keyExpr *selectors.KeyExpr) *SplitHandler {
+	h.keyExpr = keyExpr
+	return h
+}
+
+// Handle moves the message and counts it right away.
+func (h *SplitHandler) Handle(msg amqp091.Delivery) (bool, error) {
+	requeue, confirmed, err := h.HandleConfirmed(msg)
+	if err != nil {
+		return requeue, err
+	}
+	confirmed()
+	return requeue, nil
+}
+
+// HandleConfirmed moves the message. It is counted once confirmed is called.
+func (h *SplitHandler) HandleConfirmed(msg amqp091.Delivery) (bool, func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	shard, err := h.shard(msg)
+	if err != nil {
+		return true, nil, err
+	}
+	// move/publish message to the destination queue of its shard
+	destQueue := h.destQueues[shard]
+	if err = h.publisher.Publish(destQueue, mappers.DeliveryPublishing(msg)); err != nil {
+		return true, nil, err
+	}
+	if h.keyExpr == nil {
+		h.next = (h.next + 1) % len(h.destQueues)
+	}
+	return false, func() { h.count(destQueue) }, nil
+}
+
+// Counts returns the number of confirmed messages moved to each destination queue.
+func (h *SplitHandler) Counts() map[string]int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	counts := make(map[string]int, len(h.counts))
+	for destQueue, count := range h.counts {
+		counts[destQueue] = count
+	}
+	return counts
+}
+
+// region Private
+
+func (h *SplitHandler) count(destQueue string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[destQueue]++
+}
+
+func (h *SplitHandler) shard(msg amqp091.Delivery) (int, error) {
+	if h.keyExpr == nil {
+		return h.next, nil
+	}
+	key, err := h.keyExpr.Key(msg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute message key: %w", err)
+	}
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+	return jumpHash(hash.Sum64(), len(h.destQueues)), nil
+}
+
+// endregion
+
+// region Helpers
+
+// jumpHash is the jump consistent hash (Lamping, Veach). When the number of buckets grows, only the keys moved to new buckets change their bucket.
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// endregion
@@ -0,0 +1,157 @@
+package handlers_test
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/mock"
+
+	mmocks "github.com/happening-oss/rabbitmq-message-ops/internal/messaging/mocks"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/tests/util"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
+)
+
+var _ = Describe("Split handler", func() {
+	var pubMock *mmocks.Publisher
+	// published are message IDs published to each queue, in publishing order
+	var published map[string][]string
+
+	BeforeEach(func() {
+		pubMock = mmocks.NewPublisher(GinkgoT())
+		published = make(map[string][]string)
+	})
+
+	recordPublishes := func() {
+		pubMock.On(util.NameOf(pubMock.Publish), mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			queue := args.String(0)
+			published[queue] = append(published[queue], args.Get(1).(amqp091.Publishing).MessageId)
+		})
+	}
+
+	// split handles messages with message IDs <customer>-<n> and returns the shard of each customer
+	split := func(handler *handlers.SplitHandler, customers int, messagesPerCustomer int) map[string]string {
+		for n := 0; n < messagesPerCustomer; n++ {
+			for customer := 0; customer < customers; customer++ {
+				requeue, err := handler.Handle(amqp091.Delivery{
+					MessageId: fmt.Sprintf("%v-%v", customer, n),
+					Headers:   amqp091.Table{"customerId": fmt.Sprint(customer)},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(requeue).To(BeFalse())
+			}
+		}
+		shards := make(map[string]string)
+		for queue, ids := range published {
+			for _, id := range ids {
+				var customer, n int
+				_, _ = fmt.Sscanf(id, "%d-%d", &customer, &n)
+				shards[fmt.Sprint(customer)] = queue
+			}
+		}
+		return shards
+	}
+
+	It("distributes messages round-robin", func() {
+		recordPublishes()
+		handler := handlers.NewSplitHandler(pubMock, "shard1", "shard2", "shard3")
+
+		for i := 0; i < 7; i++ {
+			_, err := handler.Handle(amqp091.Delivery{MessageId: fmt.Sprint(i)})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(published).To(Equal(map[string][]string{
+			"shard1": {"0", "3", "6"},
+			"shard2": {"1", "4"},
+			"shard3": {"2", "5"},
+		}))
+		Expect(handler.Counts()).To(Equal(map[string]int{"shard1": 3, "shard2": 2, "shard3": 2}))
+	})
+
+	It("moves messages with the same key to the same shard in order", func() {
+		recordPublishes()
+		keyExpr, err := selectors.NewKeyExpr(`headers.customerId`)
+		Expect(err).ToNot(HaveOccurred())
+		handler := handlers.NewSplitHandler(pubMock, "shard1", "shard2", "shard3").WithKeyExpr(keyExpr)
+
+		split(handler, 30, 3)
+
+		Expect(published).To(HaveLen(3))
+		for _, ids := range published {
+			last := make(map[int]int)
+			for _, id := range ids {
+				var customer, n int
+				_, _ = fmt.Sscanf(id, "%d-%d", &customer, &n)
+				if previous, ok := last[customer]; ok {
+					Expect(n).To(Equal(previous + 1))
+				}
+				last[customer] = n
+			}
+			// all messages of each customer are in a single shard
+			Expect(len(ids) % 3).To(BeZero())
+		}
+	})
+
+	It("keeps keys in their shards when a shard is added, except keys moved to the new shard", func() {
+		recordPublishes()
+		keyExpr, err := selectors.NewKeyExpr(`headers.customerId`)
+		Expect(err).ToNot(HaveOccurred())
+		before := split(handlers.NewSplitHandler(pubMock, "shard1", "shard2", "shard3").WithKeyExpr(keyExpr), 100, 1)
+
+		published = make(map[string][]string)
+		after := split(handlers.NewSplitHandler(pubMock, "shard1", "shard2", "shard3", "shard4").WithKeyExpr(keyExpr), 100, 1)
+
+		moved := 0
+		for customer, shard := range after {
+			if shard == "shard4" {
+				moved++
+				continue
+			}
+			Expect(shard).To(Equal(before[customer]))
+		}
+		Expect(moved).To(BeNumerically(">", 0))
+		Expect(moved).To(BeNumerically("<", 50))
+	})
+
+	It("counts moved messages once they are confirmed", func() {
+		recordPublishes()
+		handler := handlers.NewSplitHandler(pubMock, "shard1", "shard2")
+
+		requeue, confirmed, err := handler.HandleConfirmed(amqp091.Delivery{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeFalse())
+		Expect(handler.Counts()).To(Equal(map[string]int{"shard1": 0, "shard2": 0}))
+
+		confirmed()
+		Expect(handler.Counts()).To(Equal(map[string]int{"shard1": 1, "shard2": 0}))
+	})
+
+	It("returns error if message key cannot be computed", func() {
+		keyExpr, err := selectors.NewKeyExpr(`headers.customerId`)
+		Expect(err).ToNot(HaveOccurred())
+		handler := handlers.NewSplitHandler(pubMock, "shard1", "shard2").WithKeyExpr(keyExpr)
+
+		requeue, err := handler.Handle(amqp091.Delivery{})
+		Expect(err).To(MatchError(ContainSubstring("failed to compute message key")))
+		Expect(requeue).To(BeTrue())
+	})
+
+	It("does not advance round-robin if publishing fails", func() {
+		pubMock.On(util.NameOf(pubMock.Publish), "shard1", mock.Anything).Return(errors.New("")).Once()
+		pubMock.On(util.NameOf(pubMock.Publish), "shard1", mock.Anything).Return(nil).Once()
+		handler := handlers.NewSplitHandler(pubMock, "shard1", "shard2")
+
+		requeue, err := handler.Handle(amqp091.Delivery{})
+		Expect(err).To(HaveOccurred())
+		Expect(requeue).To(BeTrue())
+
+		_, err = handler.Handle(amqp091.Delivery{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handler.Counts()).To(Equal(map[string]int{"shard1": 1, "shard2": 0}))
+	})
+})
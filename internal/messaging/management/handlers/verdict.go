@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
+)
+
+const (
+	VerdictKeep    = "keep"
+	VerdictDrop    = "drop"
+	VerdictMove    = "move"
+	VerdictReplace = "replace"
+)
+
+// Decide returns the verdict about the message, e.g. of an external process or a plugin.
+type Decide func(msg amqp091.Delivery) (Verdict, error)
+
+// VerdictHandler handles each message as decided by the verdict.
+type VerdictHandler struct {
+	publisher messaging.Publisher
+	decide    Decide
+	queues    queueCheck
+}
+
+func NewVerdictHandler(publisher messaging.Publisher, decide Decide) *VerdictHandler {
+	return &VerdictHandler{publisher: publisher, decide: decide}
+}
+
+// WithQueueCheck sets the check of the queues messages are moved to, e.g. if the queue exists. Each queue is checked once.
+func (h *VerdictHandler) WithQueueCheck(checkQueue func(queue string) error) *VerdictHandler {
+	h.queues.check = checkQueue
+	return h
+}
+
+func (h *VerdictHandler) Handle(msg amqp091.Delivery) (bool, error) {
+	requeue, _, err := h.HandleEdited(msg)
+	return requeue, err
+}
+
+func (h *VerdictHandler) HandleEdited(msg amqp091.Delivery) (bool, *amqp091.Publishing, error) {
+	verdict, err := h.decide(msg)
+	if err != nil {
+		return true, nil, err
+	}
+
+	publishing := mappers.DeliveryPublishing(msg)
+	if len(verdict.Message) > 0 {
+		if publishing, err = mappers.ParsePublishingDocument(verdict.Message); err != nil {
+			return true, nil, fmt.Errorf("invalid replacement message: %w", err)
+		}
+	}
+
+	switch verdict.Verdict {
+	case VerdictKeep:
+		return true, nil, nil
+	case VerdictDrop:
+		return false, nil, nil
+	case VerdictReplace:
+		if len(verdict.Message) == 0 {
+			return true, nil, errors.New("replace verdict without message")
+		}
+		return true, &publishing, nil
+	case VerdictMove:
+		if verdict.Queue == "" {
+			return true, nil, errors.New("move verdict without queue")
+		}
+		if err = h.queues.do(verdict.Queue); err != nil {
+			return true, nil, err
+		}
+		if err = h.publisher.Publish(verdict.Queue, publishing); err != nil {
+			return true, nil, err
+		}
+		return false, nil, nil
+	default:
+		return true, nil, fmt.Errorf("unsupported verdict %q", verdict.Verdict)
+	}
+}
+
+// region Structs
+
+// Verdict is the decision about the message. Message optionally replaces the kept or moved message.
+type Verdict struct {
+	Verdict string          `json:"verdict"`
+	Queue   string          `json:"queue,omitempty"`
+	Message json.RawMessage `json:"message,omitempty"`
+}
+
+// endregion
@@ -2,20 +2,40 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
+	"strconv"
 
 	"github.com/rabbitmq/amqp091-go"
 
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
 )
 
+const (
+	ViewFormatJSON  = "json"
+	ViewFormatTable = "table"
+)
+
+// maxTableBodyLength is the number of body characters shown in the table format.
+const maxTableBodyLength = 60
+
 type ViewHandler struct {
 	count      int
 	outputFile *os.File
+	format     string
+	viewed     int
 }
 
 func NewViewHandler(count int, outputFile *os.File) *ViewHandler {
-	return &ViewHandler{count: count, outputFile: outputFile}
+	return &ViewHandler{count: count, outputFile: outputFile, format: ViewFormatJSON}
+}
+
+// WithFormat sets the output format, either ViewFormatJSON (default) or ViewFormatTable.
+// Table shows messages in the effective delivery order, together with their priority.
+func (h *ViewHandler) WithFormat(format string) *ViewHandler {
+	h.format = format
+	return h
 }
 
 func (h *ViewHandler) Handle(msg amqp091.Delivery) (bool, error) {
@@ -24,14 +44,17 @@ func (h *ViewHandler) Handle(msg amqp091.Delivery) (bool, error) {
 		return true, nil
 	}
 
-	var encoder *json.Encoder
+	var output io.Writer = os.Stdout
 	if h.outputFile != nil {
-		encoder = json.NewEncoder(h.outputFile)
-	} else {
-		encoder = json.NewEncoder(os.Stdout)
+		output = h.outputFile
 	}
 
-	err := encoder.Encode(selectors.SubsetFromDelivery(msg)) // write message to file/stdout for viewing
+	var err error
+	if h.format == ViewFormatTable {
+		err = h.writeRow(output, msg)
+	} else {
+		err = json.NewEncoder(output).Encode(selectors.SubsetFromDelivery(msg)) // write message to file/stdout for viewing
+	}
 	if err != nil {
 		return true, err
 	}
@@ -40,3 +63,30 @@ func (h *ViewHandler) Handle(msg amqp091.Delivery) (bool, error) {
 
 	return true, nil
 }
+
+// region Helpers
+
+const tableRowFormat = "%-6v %-8v %-36v %-24v %-25v %v\n"
+
+// writeRow writes the message as a table row, preceded by the header for the first message.
+func (h *ViewHandler) writeRow(output io.Writer, msg amqp091.Delivery) error {
+	if h.viewed == 0 {
+		if _, err := fmt.Fprintf(output, tableRowFormat, "#", "PRIORITY", "MESSAGE ID", "TYPE", "TIMESTAMP", "BODY"); err != nil {
+			return err
+		}
+	}
+	h.viewed++
+
+	var timestamp string
+	if !msg.Timestamp.IsZero() {
+		timestamp = msg.Timestamp.Format("2006-01-02T15:04:05Z07:00")
+	}
+	body := []rune(string(msg.Body))
+	if len(body) > maxTableBodyLength {
+		body = append(body[:maxTableBodyLength], '…')
+	}
+	_, err := fmt.Fprintf(output, tableRowFormat, h.viewed, msg.Priority, msg.MessageId, msg.Type, timestamp, strconv.Quote(string(body)))
+	return err
+}
+
+// endregion
@@ -63,6 +63,33 @@ var _ = Describe("View handler", func() {
 {"headers":{"type":"msg.type3","userID":"user123"},"appID":"123"}
 {"headers":{"type":"msg.type4"},"timestamp":"1970-01-01T00:00:01Z","body":"body4"}
 {"body":"body5"}
+`))
+		})
+	})
+	When("writing in table format", func() {
+		It("shows messages in delivery order with their priority", func() {
+			outputFile, err := os.CreateTemp("", "")
+			Expect(err).ToNot(HaveOccurred())
+			defer func() {
+				err = os.Remove(outputFile.Name())
+				Expect(err).ToNot(HaveOccurred())
+			}()
+
+			handler := handlers.NewViewHandler(2, outputFile).WithFormat(handlers.ViewFormatTable)
+			for _, msg := range []amqp091.Delivery{
+				{MessageId: "msg-1", Priority: 5, Type: "someType", Body: []byte("body1")},
+				{MessageId: "msg-2", Body: []byte("body2")},
+				{MessageId: "msg-3"},
+			} {
+				_, err = handler.Handle(msg)
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			data, err := os.ReadFile(outputFile.Name())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal(`#      PRIORITY MESSAGE ID                           TYPE                     TIMESTAMP                 BODY
+1      5        msg-1                                someType                                           "body1"
+2      0        msg-2                                                                                   "body2"
 `))
 		})
 	})
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
+)
+
+const (
+	// WebhookFormatEnvelope posts the message as a JSON document of its properties, headers and body (see mappers.PublishingDocument).
+	WebhookFormatEnvelope = "envelope"
+	// WebhookFormatRaw posts the message body with its content type, properties and headers are mapped to HTTP headers.
+	WebhookFormatRaw = "raw"
+)
+
+const (
+	webhookPropertyPrefix = "X-Amqp-"
+	webhookHeaderPrefix   = "X-Amqp-Header-"
+	// maxWebhookResponseSize is the maximal size of a response body read as the verdict.
+	maxWebhookResponseSize = 64 * 1024 * 1024
+)
+
+// WebhookHandler posts each message to the URL and handles it as decided by the response:
+//   - 2xx responses remove the message, unless their JSON body is a verdict (see Verdict), which is applied instead,
+//   - 408, 429 and 5xx responses and failed requests are retried, the handler fails once all attempts fail,
+//   - 401, 403, 404 and 405 responses fail the handler, since they are caused by the URL or the headers rather than the message,
+//   - other responses keep the message, they are counted by status (see Kept).
+//
+// Messages are posted one at a time, in the order they are handled, so that their order is preserved.
+type WebhookHandler struct {
+	*VerdictHandler
+	ctx      context.Context
+	url      string
+	format   string
+	header   http.Header
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+
+	mu   sync.Mutex
+	kept map[int]int
+}
+
+func NewWebhookHandler(publisher messaging.Publisher, url string) *WebhookHandler {
+	h := &WebhookHandler{
+		ctx:      context.Background(),
+		url:      url,
+		format:   WebhookFormatEnvelope,
+		header:   make(http.Header),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		attempts: 1,
+		kept:     make(map[int]int),
+	}
+	h.VerdictHandler = NewVerdictHandler(publisher, h.post)
+	return h
+}
+
+// WithContext sets the context of the requests and of the backoff between them, so that cancelling it aborts a pending request.
+func (h *WebhookHandler) WithContext(ctx context.Context) *WebhookHandler {
+	h.ctx = ctx
+	return h
+}
+
+// WithFormat sets the format of posted messages (envelope or raw).
+func (h *WebhookHandler) WithFormat(format string) *WebhookHandler {
+	h.format = format
+	return h
+}
+
+// WithHeader adds the header to each request, e.g. for authorization.
+func (h *WebhookHandler) WithHeader(key, value string) *WebhookHandler {
+	h.header.Add(key, value)
+	return h
+}
+
+// WithTimeout sets the timeout of a single request.
+func (h *WebhookHandler) WithTimeout(timeout time.Duration) *WebhookHandler {
+	h.client.Timeout = timeout
+	return h
+}
+
+// WithRetries sets the number of retries of a failed request. The backoff doubles after each retry.
+func (h *WebhookHandler) WithRetries(retries int, backoff time.Duration) *WebhookHandler {
+	h.attempts = retries + 1
+	h.backoff = backoff
+	return h
+}
+
+// WithQueueCheck sets the check of the queues messages are moved to, e.g. if the queue exists. Each queue is checked once.
+func (h *WebhookHandler) WithQueueCheck(checkQueue func(queue string) error) *WebhookHandler {
+	h.VerdictHandler.WithQueueCheck(checkQueue)
+	return h
+}
+
+// Kept returns the number of messages kept by each response status.
+func (h *WebhookHandler) Kept() map[int]int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	kept := make(map[int]int, len(h.kept))
+	for status, count := range h.kept {
+		kept[status] = count
+	}
+	return kept
+}
+
+// region Private
+
+// post posts the message until the response decides about it or all attempts fail.
+func (h *WebhookHandler) post(msg amqp091.Delivery) (Verdict, error) {
+	backoff := h.backoff
+	var err error
+	for attempt := 1; attempt <= h.attempts; attempt++ {
+		if attempt > 1 {
+			if err := h.wait(backoff); err != nil {
+				return Verdict{}, err
+			}
+			backoff *= 2
+		}
+		var verdict Verdict
+		var retry bool
+		verdict, retry, err = h.request(msg)
+		if !retry {
+			return verdict, err
+		}
+	}
+	return Verdict{}, fmt.Errorf("webhook: all %v attempts failed: %w", h.attempts, err)
+}
+
+// wait waits for the backoff unless the context is cancelled.
+func (h *WebhookHandler) wait(backoff time.Duration) error {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-h.ctx.Done():
+		return fmt.Errorf("webhook: retry cancelled: %w", h.ctx.Err())
+	}
+}
+
+// request posts the message once. retry is set if the request failed temporarily.
+func (h *WebhookHandler) request(msg amqp091.Delivery) (verdict Verdict, retry bool, err error) {
+	req, err := h.buildRequest(msg)
+	if err != nil {
+		return Verdict{}, false, err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		// cancelled requests are not retried
+		return Verdict{}, h.ctx.Err() == nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseSize))
+	if err != nil {
+		return Verdict{}, true, err
+	}
+
+	switch status := resp.StatusCode; {
+	case status >= 200 && status < 300:
+		return responseVerdict(resp.Header.Get("Content-Type"), body)
+	case status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500:
+		return Verdict{}, true, fmt.Errorf("webhook: unexpected response %v", resp.Status)
+	case status == http.StatusUnauthorized || status == http.StatusForbidden || status == http.StatusNotFound || status == http.StatusMethodNotAllowed:
+		return Verdict{}, false, fmt.Errorf("webhook: request rejected with %v, please check the URL and the headers", resp.Status)
+	default:
+		h.mu.Lock()
+		h.kept[status]++
+		h.mu.Unlock()
+		return Verdict{Verdict: VerdictKeep}, false, nil
+	}
+}
+
+func (h *WebhookHandler) buildRequest(msg amqp091.Delivery) (*http.Request, error) {
+	var body []byte
+	header := h.header.Clone()
+	switch h.format {
+	case WebhookFormatEnvelope:
+		doc, err := mappers.PublishingDocument(mappers.DeliveryPublishing(msg))
+		if err != nil {
+			return nil, err
+		}
+		body = doc
+		header.Set("Content-Type", "application/json")
+	case WebhookFormatRaw:
+		body = msg.Body
+		mapProperties(header, msg)
+	default:
+		return nil, fmt.Errorf("webhook: unsupported format %q", h.format)
+	}
+
+	req, err := http.NewRequestWithContext(h.ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("webhook: invalid request: %w", err)
+	}
+	req.Header = header
+	return req, nil
+}
+
+// endregion
+
+// region Helpers
+
+// responseVerdict returns the verdict of a successful response. Responses without a JSON verdict remove the message.
+func responseVerdict(contentType string, body []byte) (Verdict, bool, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "application/json" || len(bytes.TrimSpace(body)) == 0 {
+		return Verdict{Verdict: VerdictDrop}, false, nil
+	}
+	var verdict Verdict
+	if err := json.Unmarshal(body, &verdict); err != nil {
+		return Verdict{}, false, fmt.Errorf("webhook: invalid verdict: %w", err)
+	}
+	if verdict.Verdict == "" {
+		// JSON responses which are not verdicts are plain successes
+		return Verdict{Verdict: VerdictDrop}, false, nil
+	}
+	return verdict, false, nil
+}
+
+// mapProperties maps message properties and headers to HTTP headers of a raw request.
+func mapProperties(header http.Header, msg amqp091.Delivery) {
+	properties := map[string]string{
+		"Content-Type":                           msg.ContentType,
+		"Content-Encoding":                       msg.ContentEncoding,
+		webhookPropertyPrefix + "Message-Id":     msg.MessageId,
+		webhookPropertyPrefix + "Correlation-Id": msg.CorrelationId,
+		webhookPropertyPrefix + "Type":           msg.Type,
+		webhookPropertyPrefix + "App-Id":         msg.AppId,
+		webhookPropertyPrefix + "Exchange":       msg.Exchange,
+		webhookPropertyPrefix + "Routing-Key":    msg.RoutingKey,
+	}
+	if !msg.Timestamp.IsZero() {
+		properties[webhookPropertyPrefix+"Timestamp"] = msg.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	for key, value := range properties {
+		if value != "" {
+			header.Set(key, value)
+		}
+	}
+	for key, value := range msg.Headers {
+		// headers which cannot be HTTP headers are skipped, values must be single lines
+		if strings.IndexFunc(key, invalidHeaderRune) >= 0 {
+			continue
+		}
+		header.Set(webhookHeaderPrefix+key, strings.Map(func(r rune) rune {
+			if r == '\r' || r == '\n' {
+				return ' '
+			}
+			return r
+		}, fmt.Sprint(value)))
+	}
+}
+
+func invalidHeaderRune(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.')
+}
+
+// endregion
@@ -0,0 +1,207 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	mmocks "github.com/happening-oss/rabbitmq-message-ops/internal/messaging/mocks"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
+)
+
+var _ = Describe("Webhook handler", func() {
+	var pubMock *mmocks.Publisher
+
+	// startServer starts a local HTTP stub which responds with the handler and returns its URL
+	startServer := func(handler http.HandlerFunc) string {
+		server := httptest.NewServer(handler)
+		DeferCleanup(server.Close)
+		return server.URL
+	}
+
+	BeforeEach(func() {
+		pubMock = mmocks.NewPublisher(GinkgoT())
+	})
+
+	It("posts message as JSON envelope and removes it on success", func() {
+		var doc mappers.Document
+		var authorization string
+		url := startServer(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(json.NewDecoder(r.Body).Decode(&doc)).To(Succeed())
+			w.WriteHeader(http.StatusCreated)
+		})
+		handler := handlers.NewWebhookHandler(pubMock, url).WithHeader("Authorization", "Bearer token")
+
+		requeue, err := handler.Handle(amqp091.Delivery{MessageId: "msg-1", Headers: amqp091.Table{"attempt": int64(3)}, Body: []byte("failed")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeFalse())
+		Expect(authorization).To(Equal("Bearer token"))
+		Expect(doc.MessageID).To(Equal("msg-1"))
+		Expect(doc.Headers).To(HaveKeyWithValue("attempt", BeNumerically("==", 3)))
+		Expect(doc.Body).To(MatchJSON(`"failed"`))
+	})
+
+	It("posts raw body with properties and headers mapped to HTTP headers", func() {
+		var header http.Header
+		var body []byte
+		url := startServer(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header
+			body, _ = io.ReadAll(r.Body)
+		})
+		handler := handlers.NewWebhookHandler(pubMock, url).WithFormat(handlers.WebhookFormatRaw)
+
+		msg := amqp091.Delivery{
+			MessageId:   "msg-1",
+			Type:        "some.msg.type",
+			ContentType: "text/plain",
+			Headers:     amqp091.Table{"x-death-reason": "rejected", "invalid header": "skipped"},
+			Body:        []byte("failed"),
+		}
+		requeue, err := handler.Handle(msg)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeFalse())
+		Expect(body).To(Equal([]byte("failed")))
+		Expect(header.Get("Content-Type")).To(Equal("text/plain"))
+		Expect(header.Get("X-Amqp-Message-Id")).To(Equal("msg-1"))
+		Expect(header.Get("X-Amqp-Type")).To(Equal("some.msg.type"))
+		Expect(header.Get("X-Amqp-Header-X-Death-Reason")).To(Equal("rejected"))
+		Expect(header).ToNot(HaveKey("X-Amqp-Header-Invalid Header"))
+	})
+
+	It("keeps message rejected by the service", func() {
+		url := startServer(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusConflict)
+		})
+		handler := handlers.NewWebhookHandler(pubMock, url).WithRetries(3, time.Millisecond)
+
+		requeue, err := handler.Handle(amqp091.Delivery{MessageId: "msg-1"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeTrue())
+		Expect(handler.Kept()).To(Equal(map[int]int{http.StatusConflict: 1}))
+	})
+
+	It("returns error if the service rejects the request itself", func() {
+		var requests atomic.Int32
+		url := startServer(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+		handler := handlers.NewWebhookHandler(pubMock, url).WithRetries(3, time.Millisecond)
+
+		requeue, err := handler.Handle(amqp091.Delivery{MessageId: "msg-1"})
+		Expect(err).To(MatchError(ContainSubstring("401 Unauthorized")))
+		Expect(requeue).To(BeTrue())
+		Expect(requests.Load()).To(BeEquivalentTo(1))
+		Expect(handler.Kept()).To(BeEmpty())
+	})
+
+	It("applies verdict returned by the service", func() {
+		url := startServer(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			_, _ = w.Write([]byte(`{"verdict":"replace","message":{"messageID":"msg-1","bodyFormat":"text","body":"ticket-42"}}`))
+		})
+		handler := handlers.NewWebhookHandler(pubMock, url)
+
+		requeue, edited, err := handler.HandleEdited(amqp091.Delivery{MessageId: "msg-1"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeTrue())
+		Expect(edited).ToNot(BeNil())
+		Expect(edited.Body).To(Equal([]byte("ticket-42")))
+	})
+
+	It("removes message if JSON response is not a verdict", func() {
+		url := startServer(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"ticket":42}`))
+		})
+		handler := handlers.NewWebhookHandler(pubMock, url)
+
+		requeue, err := handler.Handle(amqp091.Delivery{MessageId: "msg-1"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeFalse())
+	})
+
+	It("retries temporary failures", func() {
+		var requests atomic.Int32
+		url := startServer(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		})
+		handler := handlers.NewWebhookHandler(pubMock, url).WithRetries(2, time.Millisecond)
+
+		requeue, err := handler.Handle(amqp091.Delivery{MessageId: "msg-1"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeFalse())
+		Expect(requests.Load()).To(BeEquivalentTo(3))
+	})
+
+	It("returns error once all attempts fail", func() {
+		var requests atomic.Int32
+		url := startServer(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		handler := handlers.NewWebhookHandler(pubMock, url).WithRetries(1, time.Millisecond)
+
+		requeue, err := handler.Handle(amqp091.Delivery{MessageId: "msg-1"})
+		Expect(err).To(MatchError(ContainSubstring("all 2 attempts failed")))
+		Expect(requeue).To(BeTrue())
+		Expect(requests.Load()).To(BeEquivalentTo(2))
+	})
+
+	It("retries timed out requests", func() {
+		var requests atomic.Int32
+		url := startServer(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) == 1 {
+				time.Sleep(200 * time.Millisecond)
+			}
+		})
+		handler := handlers.NewWebhookHandler(pubMock, url).WithTimeout(50*time.Millisecond).WithRetries(1, time.Millisecond)
+
+		requeue, err := handler.Handle(amqp091.Delivery{MessageId: "msg-1"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeFalse())
+		Expect(requests.Load()).To(BeEquivalentTo(2))
+	})
+	It("stops retrying once the context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		url := startServer(func(w http.ResponseWriter, r *http.Request) {
+			cancel()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		handler := handlers.NewWebhookHandler(pubMock, url).WithContext(ctx).WithRetries(1, time.Hour)
+
+		requeue, err := handler.Handle(amqp091.Delivery{MessageId: "msg-1"})
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(requeue).To(BeTrue())
+	})
+
+	It("aborts a pending request once the context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		released := make(chan struct{})
+		url := startServer(func(w http.ResponseWriter, r *http.Request) {
+			cancel()
+			<-released
+		})
+		// the response is held until the test ends, cleanups run in reverse order, so the server is closed after it is released
+		DeferCleanup(func() { close(released) })
+		handler := handlers.NewWebhookHandler(pubMock, url).WithContext(ctx).WithRetries(3, time.Millisecond)
+
+		requeue, err := handler.Handle(amqp091.Delivery{MessageId: "msg-1"})
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(requeue).To(BeTrue())
+	})
+})
@@ -0,0 +1,263 @@
+package journal
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+type Phase string
+
+const (
+	PhaseSourceToTemp Phase = "sourceToTemp"
+	PhaseTempToSource Phase = "tempToSource"
+	PhaseFinished     Phase = "finished"
+)
+
+// Step is a step of message processing in which the operation failed.
+type Step string
+
+const (
+	StepSelect        Step = "select"
+	StepHandle        Step = "handle"
+	StepPublishTemp   Step = "publishTemp"
+	StepArchive       Step = "archive"
+	StepConfirm       Step = "confirm"
+	StepAck           Step = "ack"
+	StepPublishSource Step = "publishSource"
+	StepAckTemp       Step = "ackTemp"
+	// StepConsume means the delivery channel was closed, e.g. the queue was deleted or the connection was lost.
+	StepConsume Step = "consume"
+)
+
+const fileExtension = ".json"
+
+var ErrNotFound = errors.New("journal: operation not found")
+
+// Journal keeps the state of a single queue operation in a local file, so that the operation can be resumed after a failure.
+// All methods are safe to call on a nil Journal, in which case they do nothing.
+type Journal struct {
+	path string
+	mu   sync.Mutex
+	op   Operation
+}
+
+// New creates a journal file for the provided operation in dir. Operation ID is generated if not provided.
+func New(dir string, op Operation) (*Journal, error) {
+	if op.ID == "" {
+		id, err := newOperationID()
+		if err != nil {
+			return nil, err
+		}
+		op.ID = id
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("journal: failed to create directory: %w", err)
+	}
+	now := time.Now().UTC()
+	op.CreatedAt, op.UpdatedAt = now, now
+	if op.Phase == "" {
+		op.Phase = PhaseSourceToTemp
+	}
+
+	j := &Journal{path: filepath.Join(dir, op.ID+fileExtension), op: op}
+	if err := j.write(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Open opens an existing journal of the operation with the provided ID.
+func Open(dir, id string) (*Journal, error) {
+	path := filepath.Join(dir, filepath.Base(id)+fileExtension)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("journal: failed to read journal: %w", err)
+	}
+
+	var op Operation
+	if err = json.Unmarshal(data, &op); err != nil {
+		return nil, fmt.Errorf("journal: failed to decode journal: %w", err)
+	}
+	return &Journal{path: path, op: op}, nil
+}
+
+// DefaultDir returns the default directory where journals are stored.
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "rabbitmq-message-ops", "journal")
+}
+
+// region Public
+
+func (j *Journal) ID() string {
+	if j == nil {
+		return ""
+	}
+	return j.op.ID
+}
+
+func (j *Journal) Path() string {
+	if j == nil {
+		return ""
+	}
+	return j.path
+}
+
+// Operation returns a copy of the journaled operation.
+func (j *Journal) Operation() Operation {
+	if j == nil {
+		return Operation{}
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.op
+}
+
+// Update applies the update to the journaled operation and persists it.
+func (j *Journal) Update(update func(op *Operation)) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	update(&j.op)
+	j.op.UpdatedAt = time.Now().UTC()
+	return j.write()
+}
+
+// endregion
+
+// region Private
+
+// write atomically replaces the journal file, so that a crash never leaves a partially written journal behind.
+func (j *Journal) write() error {
+	data, err := json.MarshalIndent(j.op, "", "  ")
+	if err != nil {
+		return fmt.Errorf("journal: failed to encode journal: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*")
+	if err != nil {
+		return fmt.Errorf("journal: failed to create journal file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("journal: failed to write journal: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("journal: failed to sync journal: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("journal: failed to close journal file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), j.path); err != nil {
+		return fmt.Errorf("journal: failed to replace journal: %w", err)
+	}
+	return nil
+}
+
+func newOperationID() (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("journal: failed to generate operation ID: %w", err)
+	}
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(suffix), nil
+}
+
+// endregion
+
+// region Structs
+
+type Operation struct {
+	ID                   string            `json:"id"`
+	Command              string            `json:"command"`
+	Args                 map[string]string `json:"args,omitempty"`
+	Filter               string            `json:"filter,omitempty"`
+	Plugin               string            `json:"plugin,omitempty"`
+	SrcQueue             string            `json:"srcQueue"`
+	TempQueue            string            `json:"tempQueue"`
+	Spool                string            `json:"spool,omitempty"`
+	Archive              string            `json:"archive,omitempty"`
+	Undone               bool              `json:"undone,omitempty"`
+	Phase                Phase             `json:"phase"`
+	ProcessedMessages    int               `json:"processedMessages"`
+	SelectedMessages     int               `json:"selectedMessages"`
+	MovedMessages        int               `json:"movedMessages"`
+	LastProcessedMessage *MessageIdentity  `json:"lastProcessedMessage,omitempty"`
+	Error                string            `json:"error,omitempty"`
+	FailedStep           Step              `json:"failedStep,omitempty"`
+	Quiesce              *Quiesce          `json:"quiesce,omitempty"`
+	CreatedAt            time.Time         `json:"createdAt"`
+	UpdatedAt            time.Time         `json:"updatedAt"`
+}
+
+// Quiesce describes the source queue bindings moved to the holding queue during the operation.
+// It is cleared once the bindings are restored and the held messages are appended to the source queue.
+type Quiesce struct {
+	Vhost        string    `json:"vhost"`
+	HoldingQueue string    `json:"holdingQueue"`
+	Bindings     []Binding `json:"bindings"`
+}
+
+// Binding is a binding of the source queue to an exchange.
+type Binding struct {
+	Exchange   string                 `json:"exchange"`
+	RoutingKey string                 `json:"routingKey"`
+	Arguments  map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// MessageIdentity identifies a message without storing its content.
+type MessageIdentity struct {
+	MessageID     string `json:"messageID,omitempty"`
+	CorrelationID string `json:"correlationID,omitempty"`
+	Type          string `json:"type,omitempty"`
+	Timestamp     string `json:"timestamp,omitempty"`
+	BodySHA256    string `json:"bodySHA256,omitempty"`
+}
+
+func IdentityFromDelivery(msg amqp091.Delivery) *MessageIdentity {
+	var timestamp string
+	if !msg.Timestamp.IsZero() {
+		timestamp = msg.Timestamp.Format(time.RFC3339Nano)
+	}
+	checksum := sha256.Sum256(msg.Body)
+	return &MessageIdentity{
+		MessageID:     msg.MessageId,
+		CorrelationID: msg.CorrelationId,
+		Type:          msg.Type,
+		Timestamp:     timestamp,
+		BodySHA256:    hex.EncodeToString(checksum[:]),
+	}
+}
+
+// Matches reports whether the delivery has the same identity. Identity without body checksum is matched by message ID only.
+func (i *MessageIdentity) Matches(msg amqp091.Delivery) bool {
+	if i == nil {
+		return false
+	}
+	if i.BodySHA256 == "" {
+		return i.MessageID != "" && i.MessageID == msg.MessageId
+	}
+	return *i == *IdentityFromDelivery(msg)
+}
+
+// endregion
@@ -0,0 +1,82 @@
+package journal_test
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+)
+
+func TestJournal(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Operation journal")
+}
+
+var _ = Describe("Journal", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "")
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+	})
+
+	It("persists operation updates", func() {
+		j, err := journal.New(dir, journal.Operation{Command: "move", Args: map[string]string{"destination": "destQueue"}, SrcQueue: "srcQueue", TempQueue: "tempQueue"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(j.ID()).ToNot(BeEmpty())
+		Expect(j.Operation().Phase).To(Equal(journal.PhaseSourceToTemp))
+
+		msg := amqp091.Delivery{MessageId: "msg-1", Body: []byte("body")}
+		err = j.Update(func(op *journal.Operation) {
+			op.Phase = journal.PhaseTempToSource
+			op.ProcessedMessages = 10
+			op.LastProcessedMessage = journal.IdentityFromDelivery(msg)
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		reopened, err := journal.Open(dir, j.ID())
+		Expect(err).ToNot(HaveOccurred())
+		op := reopened.Operation()
+		Expect(op.Command).To(Equal("move"))
+		Expect(op.Args).To(HaveKeyWithValue("destination", "destQueue"))
+		Expect(op.Phase).To(Equal(journal.PhaseTempToSource))
+		Expect(op.ProcessedMessages).To(Equal(10))
+		Expect(op.LastProcessedMessage.Matches(msg)).To(BeTrue())
+		Expect(op.LastProcessedMessage.Matches(amqp091.Delivery{MessageId: "msg-1", Body: []byte("other")})).To(BeFalse())
+	})
+
+	It("persists bindings of the quiesced source queue", func() {
+		j, err := journal.New(dir, journal.Operation{Command: "purge", SrcQueue: "srcQueue"})
+		Expect(err).ToNot(HaveOccurred())
+
+		quiesce := &journal.Quiesce{
+			Vhost:        "/",
+			HoldingQueue: "holdingQueue",
+			Bindings:     []journal.Binding{{Exchange: "events", RoutingKey: "orders.#", Arguments: map[string]interface{}{"x-match": "all"}}},
+		}
+		Expect(j.Update(func(op *journal.Operation) { op.Quiesce = quiesce })).To(Succeed())
+
+		reopened, err := journal.Open(dir, j.ID())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reopened.Operation().Quiesce).To(Equal(quiesce))
+	})
+
+	It("returns error when operation doesn't exist", func() {
+		_, err := journal.Open(dir, "missing")
+		Expect(err).To(MatchError(journal.ErrNotFound))
+	})
+
+	It("ignores updates when journal is nil", func() {
+		var j *journal.Journal
+		Expect(j.Update(func(op *journal.Operation) { op.Phase = journal.PhaseFinished })).To(Succeed())
+		Expect(j.ID()).To(BeEmpty())
+	})
+})
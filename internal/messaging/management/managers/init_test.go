@@ -1,11 +1,16 @@
 package managers_test
 
 import (
+	"slices"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/mocks"
 )
 
 func TestHandlers(t *testing.T) {
@@ -24,3 +29,144 @@ func initReadChannel(srcMessages []amqp091.Delivery) <-chan amqp091.Delivery {
 }
 
 // endregion
+
+// region Structs
+
+// reconnectingConsumer is a consumer mock which can reconnect.
+type reconnectingConsumer struct {
+	*mocks.Consumer
+	*mocks.Reconnector
+}
+
+// prefetchingConsumer is a consumer mock which can limit the number of prefetched messages.
+type prefetchingConsumer struct {
+	*mocks.Consumer
+	*mocks.Prefetcher
+}
+
+// reconnectingPublisher is a publisher mock which can reconnect.
+type reconnectingPublisher struct {
+	*mocks.Publisher
+	*mocks.Reconnector
+}
+
+// reconnectingPipelinedPublisher is a pipelined publisher mock which can reconnect.
+type reconnectingPipelinedPublisher struct {
+	*mocks.PipelinedPublisher
+	*mocks.Reconnector
+}
+
+// fakeBroker is an in-memory broker. Priority queues deliver messages by priority, keeping the FIFO order of messages with the same priority.
+// Only one queue is consumed at a time, consuming another queue stops the previous consumer.
+type fakeBroker struct {
+	mu          sync.Mutex
+	queues      map[string][]amqp091.Publishing
+	maxPriority map[string]uint8
+	tag         uint64
+	stop        chan struct{}
+	consumer    sync.WaitGroup
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{queues: make(map[string][]amqp091.Publishing), maxPriority: make(map[string]uint8)}
+}
+
+func (b *fakeBroker) Declare(queue string, maxPriority uint8) {
+	b.maxPriority[queue] = maxPriority
+}
+
+func (b *fakeBroker) Publish(queue string, msg amqp091.Publishing) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.queues[queue] = append(b.queues[queue], msg)
+	return nil
+}
+
+func (b *fakeBroker) Consume(queue string) (<-chan amqp091.Delivery, error) {
+	b.stopConsumer()
+	stop := make(chan struct{})
+	b.stop = stop
+	deliveries := make(chan amqp091.Delivery)
+
+	b.consumer.Add(1)
+	go func() {
+		defer b.consumer.Done()
+		for {
+			msg, ok := b.pop(queue)
+			if !ok {
+				select {
+				case <-stop:
+					return
+				case <-time.After(time.Millisecond):
+					continue
+				}
+			}
+			select {
+			case deliveries <- b.delivery(msg):
+			case <-stop:
+				b.mu.Lock()
+				b.queues[queue] = append([]amqp091.Publishing{msg}, b.queues[queue]...)
+				b.mu.Unlock()
+				return
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+func (b *fakeBroker) MessageCount(queue string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.queues[queue]), nil
+}
+
+// Drain returns the messages of the queue in the delivery order.
+func (b *fakeBroker) Drain(queue string) []string {
+	b.stopConsumer()
+	var ids []string
+	for msg, ok := b.pop(queue); ok; msg, ok = b.pop(queue) {
+		ids = append(ids, msg.MessageId)
+	}
+	return ids
+}
+
+func (b *fakeBroker) Err() error   { return nil }
+func (b *fakeBroker) Close() error { return nil }
+
+func (b *fakeBroker) Ack(uint64, bool) error        { return nil }
+func (b *fakeBroker) Nack(uint64, bool, bool) error { return nil }
+func (b *fakeBroker) Reject(uint64, bool) error     { return nil }
+
+func (b *fakeBroker) pop(queue string) (amqp091.Publishing, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	messages := b.queues[queue]
+	if len(messages) == 0 {
+		return amqp091.Publishing{}, false
+	}
+	effective := func(msg amqp091.Publishing) uint8 { return min(msg.Priority, b.maxPriority[queue]) }
+	next := 0
+	for i, msg := range messages {
+		if effective(msg) > effective(messages[next]) {
+			next = i
+		}
+	}
+	msg := messages[next]
+	b.queues[queue] = slices.Delete(messages, next, next+1)
+	b.tag++
+	return msg, true
+}
+
+func (b *fakeBroker) delivery(msg amqp091.Publishing) amqp091.Delivery {
+	return amqp091.Delivery{Acknowledger: b, DeliveryTag: b.tag, MessageId: msg.MessageId, Priority: msg.Priority, Body: msg.Body}
+}
+
+func (b *fakeBroker) stopConsumer() {
+	if b.stop != nil {
+		close(b.stop)
+		b.stop = nil
+	}
+	b.consumer.Wait()
+}
+
+// endregion
@@ -0,0 +1,105 @@
+package managers
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+)
+
+const (
+	// defaultIdleTimeout ends a phase with an unknown number of messages, which can only end once the queue is idle.
+	defaultIdleTimeout = time.Second
+	// defaultCountedIdleTimeout is only a safety net for a phase with a known number of messages, so it tolerates slow handlers and brokers.
+	defaultCountedIdleTimeout = 30 * time.Second
+)
+
+// phaseEnd detects the end of a processing phase. The phase ends once all messages present in the queue at the start of the phase are processed.
+// If the number of messages is unknown, or if no message arrives for idleTimeout, the phase ends when the queue is idle.
+// A zero idleTimeout is replaced by a default which depends on whether the number of messages is known.
+// Messages published to the queue during the phase are not counted, so they are left in front of the messages moved back by the next phase.
+type phaseEnd struct {
+	log         *slog.Logger
+	queue       string
+	expected    int
+	idleTimeout time.Duration
+}
+
+func newPhaseEnd(log *slog.Logger, counter messaging.MessageCounter, idleTimeout time.Duration, queue string) *phaseEnd {
+	end := &phaseEnd{log: log, queue: queue, expected: -1, idleTimeout: idleTimeout}
+	if end.idleTimeout == 0 {
+		end.idleTimeout = defaultIdleTimeout
+	}
+	if counter == nil {
+		return end
+	}
+
+	count, err := counter.MessageCount(queue)
+	if err != nil {
+		log.Warn("failed to read the number of messages in the queue, falling back to the idle timeout", slog.Any("error", err), slog.String("queue", queue), slog.Duration("idleTimeout", end.idleTimeout))
+		return end
+	}
+	end.expected = count
+	if idleTimeout == 0 {
+		end.idleTimeout = defaultCountedIdleTimeout
+	}
+	log.Info("messages to process", slog.String("queue", queue), slog.Int("messages", count))
+	return end
+}
+
+// reached reports whether all messages present at the start of the phase are processed.
+func (e *phaseEnd) reached(processed int) bool {
+	return e.expected >= 0 && processed >= e.expected
+}
+
+// idle returns a channel which fires if no message arrives for idleTimeout.
+func (e *phaseEnd) idle() <-chan time.Time {
+	return time.After(e.idleTimeout)
+}
+
+// onIdle reports the phase ended by the idle timeout before all expected messages were processed.
+func (e *phaseEnd) onIdle(processed int) {
+	if e.expected < 0 {
+		return
+	}
+	// logged as an error, so that it is reported with the default log level
+	e.log.Error("idle timeout reached before all messages were processed. Some messages may have been left unprocessed",
+		slog.String("queue", e.queue),
+		slog.Int("expectedMessages", e.expected),
+		slog.Int("processedMessages", processed),
+		slog.Duration("idleTimeout", e.idleTimeout),
+	)
+}
+
+// phaseProgress tracks the progress of a phase across reconnections.
+type phaseProgress struct {
+	processed  int
+	selected   int
+	last       amqp091.Delivery
+	handled    handledMessages
+	priorities *priorityOrder
+	// stopped is set once stop is requested, remaining messages are then not processed
+	stopped bool
+}
+
+func (p *phaseProgress) acked(msg amqp091.Delivery) {
+	p.last = msg
+}
+
+// connectionLost drains the pipeline of the lost connection. Messages with confirmed publishings are expected to be redelivered,
+// the others are processed again. It returns the number of messages which are processed again.
+func (p *phaseProgress) connectionLost(pipeline *ackPipeline) int {
+	var unconfirmed int
+	pipeline.drain(func(msg amqp091.Delivery, confirmed bool) {
+		if confirmed {
+			p.handled.unacked(msg)
+			return
+		}
+		unconfirmed++
+	})
+	p.processed -= unconfirmed
+	p.priorities.reset()
+	return unconfirmed
+}
@@ -0,0 +1,152 @@
+package managers
+
+import (
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+)
+
+// ackPipeline acknowledges deliveries in the delivery order, once all messages published while handling them are confirmed.
+// With publishers that wait for each confirmation, deliveries are acknowledged right away.
+type ackPipeline struct {
+	publishers []messaging.Publisher
+	pending    []pendingAck
+}
+
+func newAckPipeline(publishers ...messaging.Publisher) *ackPipeline {
+	return &ackPipeline{publishers: publishers}
+}
+
+// push adds the handled delivery to the pipeline. If set, onConfirmed is called once the messages published while handling it are confirmed.
+func (p *ackPipeline) push(msg amqp091.Delivery, onConfirmed func()) {
+	var confirmations []messaging.Confirmation
+	for _, publisher := range p.publishers {
+		if pipelined, ok := publisher.(messaging.PipelinedPublisher); ok {
+			confirmations = append(confirmations, pipelined.Confirmation())
+		}
+	}
+
+	var confirmation messaging.Confirmation = confirmed{}
+	switch len(confirmations) {
+	case 0:
+	case 1:
+		confirmation = confirmations[0]
+	default:
+		confirmation = newJoinedConfirmation(confirmations)
+	}
+	p.pending = append(p.pending, pendingAck{msg: msg, confirmation: confirmation, onConfirmed: onConfirmed})
+}
+
+// pushConfirmed adds the delivery which published nothing to the pipeline.
+func (p *ackPipeline) pushConfirmed(msg amqp091.Delivery) {
+	p.pending = append(p.pending, pendingAck{msg: msg, confirmation: confirmed{}})
+}
+
+// done returns a channel which is closed when the first pending delivery can be acknowledged, nil if nothing is pending.
+func (p *ackPipeline) done() <-chan struct{} {
+	if len(p.pending) == 0 {
+		return nil
+	}
+	return p.pending[0].confirmation.Done()
+}
+
+// ack acknowledges confirmed deliveries from the front of the pipeline. If wait is set, it waits for all pending deliveries.
+// In case of an error, the failed delivery is returned together with the information whether its publishings were confirmed.
+func (p *ackPipeline) ack(wait bool, onAck func(msg amqp091.Delivery)) (failed amqp091.Delivery, confirmed bool, err error) {
+	for len(p.pending) > 0 {
+		head := p.pending[0]
+		if !wait {
+			select {
+			case <-head.confirmation.Done():
+			default:
+				return amqp091.Delivery{}, false, nil
+			}
+		}
+		if err = head.confirmation.Err(); err != nil {
+			return head.msg, false, err
+		}
+		p.pending = p.pending[1:]
+		if head.onConfirmed != nil {
+			head.onConfirmed()
+		}
+		if err = head.msg.Ack(false); err != nil {
+			return head.msg, true, err
+		}
+		onAck(head.msg)
+	}
+	return amqp091.Delivery{}, false, nil
+}
+
+// drain waits for the confirmations of all pending deliveries and removes them from the pipeline without acknowledging them.
+func (p *ackPipeline) drain(onDrain func(msg amqp091.Delivery, confirmed bool)) {
+	for _, pending := range p.pending {
+		confirmed := pending.confirmation.Err() == nil
+		if confirmed && pending.onConfirmed != nil {
+			pending.onConfirmed()
+		}
+		onDrain(pending.msg, confirmed)
+	}
+	p.pending = nil
+}
+
+// region Helpers
+
+// handle handles the message with the handler. Handlers recording the effects of handling once the published messages are confirmed
+// (see handlers.ConfirmingHandler) also return the function to call on confirmation.
+func handle(handler handlers.MessageHandler, msg amqp091.Delivery) (bool, func(), error) {
+	if confirming, ok := handler.(handlers.ConfirmingHandler); ok {
+		return confirming.HandleConfirmed(msg)
+	}
+	requeue, err := handler.Handle(msg)
+	return requeue, nil, err
+}
+
+// endregion
+
+// region Structs
+
+type pendingAck struct {
+	msg          amqp091.Delivery
+	confirmation messaging.Confirmation
+	onConfirmed  func()
+}
+
+// confirmed is a confirmation of messages that were already confirmed while publishing.
+type confirmed struct{}
+
+var closedCh = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+func (confirmed) Done() <-chan struct{} { return closedCh }
+func (confirmed) Err() error            { return nil }
+
+// joinedConfirmation is done once all joined confirmations are done. It fails with the first failed one.
+type joinedConfirmation struct {
+	done chan struct{}
+	err  error
+}
+
+func newJoinedConfirmation(confirmations []messaging.Confirmation) *joinedConfirmation {
+	c := &joinedConfirmation{done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		for _, confirmation := range confirmations {
+			if err := confirmation.Err(); err != nil && c.err == nil {
+				c.err = err
+			}
+		}
+	}()
+	return c
+}
+
+func (c *joinedConfirmation) Done() <-chan struct{} { return c.done }
+func (c *joinedConfirmation) Err() error {
+	<-c.done
+	return c.err
+}
+
+// endregion
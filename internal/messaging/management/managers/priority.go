@@ -0,0 +1,39 @@
+package managers
+
+import "github.com/rabbitmq/amqp091-go"
+
+// priorityOrder tracks the priorities of messages delivered from a priority queue.
+// Priority queue delivers messages by their effective priority, highest first, keeping the FIFO order of messages with the same priority.
+// A message with a higher priority delivered after a lower one was published during the operation and jumped ahead of older messages.
+type priorityOrder struct {
+	maxPriority uint8
+	lowest      int
+}
+
+// newPriorityOrder returns nil (no tracking) if the queue is not a priority queue.
+func newPriorityOrder(maxPriority int) *priorityOrder {
+	if maxPriority <= 0 {
+		return nil
+	}
+	return &priorityOrder{maxPriority: uint8(min(maxPriority, 255)), lowest: -1}
+}
+
+// observe reports whether the message was delivered in the priority order.
+func (o *priorityOrder) observe(msg amqp091.Delivery) bool {
+	if o == nil {
+		return true
+	}
+	priority := int(min(msg.Priority, o.maxPriority))
+	if o.lowest >= 0 && priority > o.lowest {
+		return false
+	}
+	o.lowest = priority
+	return true
+}
+
+// reset forgets the observed priorities, since unacknowledged messages are redelivered after reconnecting.
+func (o *priorityOrder) reset() {
+	if o != nil {
+		o.lowest = -1
+	}
+}
@@ -2,6 +2,8 @@ package managers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"time"
 
@@ -9,27 +11,30 @@ import (
 
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/spool"
 )
 
 const (
 	partialQueueManagementCtxCancelHelpMsg = `Source queue has potentially been partially managed. 
 Please check if some messages have been moved from the source queue to temporary queue.
-Try to manage queue again and specify the --tempQueue parameter with the currently used temporary queue.
+Try to manage queue again with the "resume <operationID>" command or specify the --tempQueue parameter with the currently used temporary queue.
 That will cause QueueManager to continue processing from the last processed message that caused error and move all tempQueue messages (also those that were moved to tempQueue during the failed command) to source queue when finished, preserving the order.`
 	partialQueueManagementHelpMsg = `Source queue has potentially been partially managed. 
 Please check if some messages have been moved from the source queue to temporary queue.
 Please check if the last processed message (the one that caused the error, you can do that using "view --count=1") is requeued back to the front of the source queue.
 If message is not at the front, please move message to the front of the source queue manually.
-If some messages have been moved and last processed message is at the front, try to manage queue again and specify the --tempQueue parameter with the currently used temporary queue.
+If some messages have been moved and last processed message is at the front, try to manage queue again with the "resume <operationID>" command or specify the --tempQueue parameter with the currently used temporary queue.
 That will cause QueueManager to continue processing from the last processed message that caused error and move all tempQueue messages (also those that were moved to tempQueue during the failed command) to source queue when finished, preserving the order.
-If publishing to the destination queue (move/copy commands) succeeded, but acknowledging the message failed, please manually remove the duplicated message from the source or destination queue.`
-	partialTempQueueMoveHelpMsg = `Please manually move remaining messages from the temporary queue to source queue (use "move" command).
+If publishing to the destination queue (move/copy commands) succeeded, but acknowledging the message failed, please manually remove the duplicated message from the source or destination queue.
+If publisher confirms are pipelined (--confirm-window > 1), messages handled after the last processed message may have been published to the temporary or destination queue without being acknowledged. Please check both queues for duplicates.`
+	partialTempQueueMoveHelpMsg = `Please move remaining messages from the temporary queue to source queue with the "resume <operationID>" command or manually (use "move" command).
 Before doing that, please check if the last processed message (the one that caused the error, you can do that using "view --count=1") is requeued back to the front of the temporary queue.
 If message is not at the front, please move message to the front of the temporary queue manually.
 If publishing to the source queue succeeded, but acknowledging the message failed, please manually remove the duplicated message from the temporary or source queue.`
-	partialTempQueueMoveCtxCancelHelpMsg = `Please manually move remaining messages from the temporary queue to source queue (use "move" command).`
+	partialTempQueueMoveCtxCancelHelpMsg = `Please move remaining messages from the temporary queue to source queue with the "resume <operationID>" command or manually (use "move" command).`
 )
 
 type QueueManager struct {
@@ -39,161 +44,638 @@ type QueueManager struct {
 	publisher messaging.Publisher
 	selector  selectors.Selector
 	tempQueue string
+	journal   *journal.Journal
+
+	counter     messaging.MessageCounter
+	idleTimeout time.Duration
+	maxPriority int
+	spool       *spool.Spool
+	stop        <-chan struct{}
+	archive     *spool.Spool
+	insertions  *insertions
+	// positionBase is the position of the first source queue message in the original queue, non-zero for resumed operations
+	positionBase int
 }
 
 func NewQueueManager(consumer messaging.Consumer, log *slog.Logger, handler handlers.MessageHandler, publisher messaging.Publisher, selector selectors.Selector, tempQueue string) *QueueManager {
 	return &QueueManager{consumer: consumer, log: log, handler: handler, publisher: publisher, selector: selector, tempQueue: tempQueue}
 }
 
+// WithJournal makes the manager record the operation progress in the provided journal.
+func (m *QueueManager) WithJournal(j *journal.Journal) *QueueManager {
+	m.journal = j
+	return m
+}
+
+// WithMessageCounter makes the manager end each phase once all messages present in the queue at the start of the phase are processed.
+// Idle timeout is then only used as a safety net.
+func (m *QueueManager) WithMessageCounter(counter messaging.MessageCounter) *QueueManager {
+	m.counter = counter
+	return m
+}
+
+// WithIdleTimeout sets how long the manager waits for the next message before ending the phase.
+// Zero selects a default, which is longer if the number of messages is known.
+func (m *QueueManager) WithIdleTimeout(idleTimeout time.Duration) *QueueManager {
+	m.idleTimeout = idleTimeout
+	return m
+}
+
+// WithMaxPriority enables the priority-aware mode for source queues declared with x-max-priority.
+// Messages are delivered by priority, so the manager warns about messages which break the priority order, since they were published during the operation.
+func (m *QueueManager) WithMaxPriority(maxPriority int) *QueueManager {
+	m.maxPriority = maxPriority
+	return m
+}
+
+// WithStop makes the manager stop processing source queue messages once stop is closed.
+// Remaining messages are only moved through the temporary queue without being selected or handled, so that the original order is preserved.
+func (m *QueueManager) WithStop(stop <-chan struct{}) *QueueManager {
+	m.stop = stop
+	return m
+}
+
+// WithArchive makes the manager archive messages removed from the source queue, together with their original position, before they are acknowledged.
+// Removal of archived messages can be undone with WithInsertions.
+func (m *QueueManager) WithArchive(archive *spool.Spool) *QueueManager {
+	m.archive = archive
+	return m
+}
+
+// WithInsertions makes the manager insert the archived messages back into the source queue at their original positions.
+// Messages are only inserted, so the manager must not select any source queue message. Lost connections are not recovered from,
+// since inserted messages which were not confirmed cannot be inserted again.
+func (m *QueueManager) WithInsertions(archive *spool.Reader) *QueueManager {
+	m.insertions = newInsertions(archive)
+	return m
+}
+
+// WithSpool makes the manager keep messages in the local spool instead of the temporary queue.
+// The spool path is reported in place of the temporary queue.
+func (m *QueueManager) WithSpool(s *spool.Spool) *QueueManager {
+	m.spool = s
+	m.tempQueue = s.Path()
+	return m
+}
+
 // region Public
 
 func (m *QueueManager) Manage(ctx context.Context, srcQueue string) error {
-	messages, err := m.consumer.Consume(srcQueue)
-	if err != nil {
-		return err
-	}
+	m.record(func(op *journal.Operation) {
+		op.Phase = journal.PhaseSourceToTemp
+		if m.spool != nil {
+			op.Spool = m.spool.Path()
+		} else {
+			op.TempQueue = m.tempQueue
+		}
+	})
+
+	end := newPhaseEnd(m.log, m.counter, m.idleTimeout, srcQueue)
 
 	m.log.Info("processing source queue")
 
 	startTime := time.Now()
-	var processedMessages, selectedMessages int
-	var lastProcessedMessage amqp091.Delivery
+	progress := &phaseProgress{priorities: newPriorityOrder(m.maxPriority)}
 
 	defer func() {
 		m.log.Info("processing source queue finished",
-			slog.Int("processedMessages", processedMessages),
-			slog.Int("selectedMessages", selectedMessages),
+			slog.Int("processedMessages", progress.processed),
+			slog.Int("selectedMessages", progress.selected),
 			slog.Duration("duration", time.Since(startTime)),
 		)
+		m.record(func(op *journal.Operation) {
+			op.ProcessedMessages = m.positionBase + progress.processed
+			op.SelectedMessages = progress.selected
+		})
 	}()
 
-loop:
+	// continue from the last processed message if the connection is lost
 	for {
+		err := m.processSrcQueue(ctx, srcQueue, end, progress, startTime)
+		if err == nil {
+			break
+		}
+		if !m.connectionLost(err) {
+			return err
+		}
+		if err = m.reconnect(ctx, err, progress.last, srcQueue, partialQueueManagementHelpMsg); err != nil {
+			return err
+		}
+	}
+
+	// archived messages which were at the end of the source queue
+	if err := m.insertRest(srcQueue); err != nil {
+		return err
+	}
+
+	// move messages back to source queue from temporary queue
+	return m.moveTempToSource(ctx, m.tempQueue, srcQueue)
+}
+
+// Resume continues the journaled operation from the phase in which it stopped.
+func (m *QueueManager) Resume(ctx context.Context, srcQueue string) error {
+	op := m.journal.Operation()
+	switch op.Phase {
+	case journal.PhaseSourceToTemp, "":
+		// positions of archived messages continue from the previous run
+		m.positionBase = op.ProcessedMessages
+		switch op.FailedStep {
+		case journal.StepSelect, journal.StepHandle, journal.StepPublishTemp, journal.StepArchive, journal.StepConfirm:
+			// failed message was requeued to the front of the source queue
+			m.positionBase--
+		}
+		m.positionBase = max(m.positionBase, 0)
+		return m.Manage(ctx, srcQueue)
+	case journal.PhaseTempToSource:
+		return m.Restore(ctx, srcQueue)
+	default:
+		return fmt.Errorf("operation %v cannot be resumed from phase %v", m.journal.ID(), op.Phase)
+	}
+}
+
+// Restore moves all messages from the temporary queue back to the source queue.
+func (m *QueueManager) Restore(ctx context.Context, srcQueue string) error {
+	return m.moveTempToSource(ctx, m.tempQueue, srcQueue)
+}
+
+func (m *QueueManager) moveTempToSource(ctx context.Context, tempQueue, srcQueue string) error {
+	m.record(func(op *journal.Operation) {
+		op.Phase = journal.PhaseTempToSource
+	})
+
+	end := newPhaseEnd(m.log, m.tempCounter(), m.idleTimeout, tempQueue)
+
+	m.log.Info("moving messages from temporary to source queue")
+
+	startTime := time.Now()
+	progress := &phaseProgress{}
+
+	defer func() {
+		m.log.Info("moving messages from temporary to source queue finished",
+			slog.Int("movedMessages", progress.processed),
+			slog.Duration("duration", time.Since(startTime)),
+		)
+		m.record(func(op *journal.Operation) {
+			op.MovedMessages = progress.processed
+		})
+	}()
+
+	// continue from the last moved message if the connection is lost
+	for {
+		err := m.processTempQueue(ctx, tempQueue, srcQueue, end, progress, startTime)
+		if err == nil {
+			break
+		}
+		if !m.connectionLost(err) {
+			return err
+		}
+		if err = m.reconnect(ctx, err, progress.last, srcQueue, partialTempQueueMoveHelpMsg); err != nil {
+			return err
+		}
+	}
+
+	m.record(func(op *journal.Operation) {
+		op.Phase = journal.PhaseFinished
+		op.Error = ""
+		op.FailedStep = ""
+	})
+	return nil
+}
+
+// endregion
+
+// region Private
+
+// processSrcQueue processes the source queue until the end of the phase.
+// If the connection is lost, the error is returned without handling it, so that processing continues after reconnecting.
+func (m *QueueManager) processSrcQueue(ctx context.Context, srcQueue string, end *phaseEnd, progress *phaseProgress, startTime time.Time) error {
+	messages, err := m.consumer.Consume(srcQueue)
+	if err != nil {
+		m.recordErr("", err, amqp091.Delivery{})
+		return err
+	}
+
+	// spooled and archived messages are confirmed once they are synced to disk
+	publishers := []messaging.Publisher{m.publisher}
+	if m.spool != nil {
+		publishers = append(publishers, m.spool)
+	}
+	if m.archive != nil {
+		publishers = append(publishers, m.archive)
+	}
+	pipeline := newAckPipeline(publishers...)
+
+	// unacknowledged messages are redelivered after reconnecting, those with unconfirmed publishings are processed again
+	connectionLost := func(err error) error {
+		unconfirmed := progress.connectionLost(pipeline)
+		if unconfirmed > 0 {
+			m.log.Warn("publishings of messages processed before the connection was lost were not confirmed. They will be processed again and may be duplicated in the temporary or destination queue",
+				slog.Int("messages", unconfirmed),
+				slog.String("srcQueue", srcQueue),
+				slog.String("tempQueue", m.tempQueue),
+			)
+		}
+		return err
+	}
+	stop := m.stop
+	if progress.stopped {
+		stop = nil
+	}
+	// acknowledge (purge/remove) source queue messages once their publishings are confirmed
+	ackConfirmed := func(wait bool) error {
+		msg, confirmed, err := pipeline.ack(wait, progress.acked)
+		if err == nil {
+			return nil
+		}
+		if !confirmed {
+			if m.connectionLost(err) {
+				return connectionLost(err)
+			}
+			return m.handleMsgProcessingError(journal.StepConfirm, "error occurred while confirming published messages", err, msg, srcQueue)
+		}
+		if err = m.ackErr(err); m.connectionLost(err) {
+			progress.handled.unacked(msg)
+			return connectionLost(err)
+		}
+		m.logMsgProcessingError("error occurred while acknowledging message", err, msg, srcQueue)
+		m.recordErr(journal.StepAck, err, msg)
+		return err
+	}
+	// messages handled before the failed one are acknowledged first, so that only the failed message is requeued
+	handleErr := func(step journal.Step, errMsg string, err error, msg amqp091.Delivery, selected bool) error {
+		if m.connectionLost(err) {
+			// failed message is redelivered and processed again after reconnecting
+			progress.processed--
+			if selected {
+				progress.selected--
+			}
+			return connectionLost(err)
+		}
+		if ackErr := ackConfirmed(true); ackErr != nil {
+			return ackErr
+		}
+		return m.handleMsgProcessingError(step, errMsg, err, msg, srcQueue)
+	}
+
+loop:
+	for !end.reached(progress.processed) {
+		// stop takes precedence over messages which are already waiting
+		select {
+		case <-stop:
+			stop = nil
+			progress.stopped = true
+			m.log.Warn("stop requested, remaining source queue messages are moved through the temporary queue without being processed to preserve the order. Press Ctrl+C again to abort",
+				slog.Int("processedMessages", progress.processed),
+				slog.String("srcQueue", srcQueue),
+				slog.String("tempQueue", m.tempQueue),
+				slog.String("operationID", m.journal.ID()),
+			)
+		default:
+		}
+
 		select {
-		case msg := <-messages:
-			processedMessages++
-			selected, err := m.selector.IsSelected(msg)
+		case msg, ok := <-messages:
+			if !ok {
+				err = m.consumer.Err()
+				if m.connectionLost(err) {
+					return connectionLost(err)
+				}
+				// deliveries handled before the closure can no longer be acknowledged, they are redelivered to the source queue
+				m.log.Error("delivery channel closed while processing source queue",
+					slog.Any("error", err),
+					slog.Any("lastProcessedMessage", progress.last),
+					slog.String("srcQueue", srcQueue),
+					slog.String("tempQueue", m.tempQueue),
+					slog.String("help", partialQueueManagementHelpMsg),
+					slog.String("operationID", m.journal.ID()),
+				)
+				m.recordErr(journal.StepConsume, err, progress.last)
+				return err
+			}
+			if progress.handled.isRedelivery(msg) {
+				// message was already processed before the connection was lost
+				pipeline.pushConfirmed(msg)
+				if err = ackConfirmed(false); err != nil {
+					return err
+				}
+				continue
+			}
+			progress.processed++
+			err = m.insertions.insertBefore(progress.processed-1, func(archived amqp091.Publishing) error {
+				return m.tempPublisher().Publish(m.tempQueue, archived)
+			})
 			if err != nil {
-				return m.handleMsgProcessingError("error occurred while checking if message is selected", err, msg, srcQueue)
+				return handleErr(journal.StepPublishTemp, "error occurred while inserting archived message", err, msg, false)
+			}
+			if !progress.priorities.observe(msg) {
+				m.log.Warn("message was delivered after messages with a lower priority. It was probably published during the operation, so it is placed in front of older messages with a lower priority",
+					slog.Any("msg", msg),
+					slog.String("srcQueue", srcQueue),
+				)
+			}
+			// messages are not processed after stop is requested
+			selected := false
+			if !progress.stopped {
+				if selected, err = m.selector.IsSelected(msg); err != nil {
+					return handleErr(journal.StepSelect, "error occurred while checking if message is selected", err, msg, false)
+				}
 			}
 
 			requeue := true
+			var edited *amqp091.Publishing
+			var onConfirmed func()
 			if selected {
-				selectedMessages++
+				progress.selected++
 				// process message with the provided handler
-				requeue, err = m.handler.Handle(msg)
+				if editing, ok := m.handler.(handlers.EditingHandler); ok {
+					requeue, edited, err = editing.HandleEdited(msg)
+				} else {
+					requeue, onConfirmed, err = handle(m.handler, msg)
+				}
 				if err != nil {
-					return m.handleMsgProcessingError("error occurred while handling message", err, msg, srcQueue)
+					return handleErr(journal.StepHandle, "error occurred while handling message", err, msg, selected)
 				}
 			}
 
+			if !requeue && m.archive != nil {
+				// archived message is synced to disk before the source queue message is acknowledged
+				err = m.archive.Append(spool.Record{Queue: srcQueue, Position: m.positionBase + progress.processed - 1, Publishing: mappers.DeliveryPublishing(msg)})
+				if err != nil {
+					return handleErr(journal.StepArchive, "error occurred while archiving removed message", err, msg, selected)
+				}
+			}
 			if requeue {
-				// move/publish message to the temporary queue
-				err = m.publisher.Publish(m.tempQueue, mappers.DeliveryPublishing(msg))
+				// move/publish message (or its edited replacement) to the temporary queue
+				publishing := mappers.DeliveryPublishing(msg)
+				if edited != nil {
+					publishing = *edited
+				}
+				err = m.tempPublisher().Publish(m.tempQueue, publishing)
 				if err != nil {
-					return m.handleMsgProcessingError("error occurred while publishing message to temporary queue", err, msg, srcQueue)
+					return handleErr(journal.StepPublishTemp, "error occurred while publishing message to temporary queue", err, msg, selected)
 				}
 			}
-			err = msg.Ack(false) // purge/remove message from the source queue
-			if err != nil {
-				m.logMsgProcessingError("error occurred while acknowledging message", err, msg, srcQueue)
+			pipeline.push(msg, onConfirmed)
+			if err = ackConfirmed(false); err != nil {
 				return err
 			}
-			if processedMessages%1000 == 0 {
+			if progress.processed%1000 == 0 {
 				m.log.Info("processing source queue progress",
-					slog.Int("processedMessages", processedMessages),
-					slog.Int("selectedMessages", selectedMessages),
+					slog.Int("processedMessages", progress.processed),
+					slog.Int("selectedMessages", progress.selected),
 					slog.Duration("duration", time.Since(startTime)),
 				)
+				m.recordProgress(msg, func(op *journal.Operation) {
+					op.ProcessedMessages = m.positionBase + progress.processed
+					op.SelectedMessages = progress.selected
+				})
+			}
+		case <-pipeline.done():
+			if err = ackConfirmed(false); err != nil {
+				return err
 			}
-			lastProcessedMessage = msg
 		case <-ctx.Done():
+			if err = ackConfirmed(true); err != nil {
+				return err
+			}
 			m.log.Error("context cancelled while processing source queue",
 				slog.Any("error", ctx.Err()),
-				slog.Any("lastProcessedMessage", lastProcessedMessage),
+				slog.Any("lastProcessedMessage", progress.last),
 				slog.String("srcQueue", srcQueue),
 				slog.String("tempQueue", m.tempQueue),
 				slog.String("help", partialQueueManagementCtxCancelHelpMsg),
+				slog.String("operationID", m.journal.ID()),
 			)
+			m.recordErr("", ctx.Err(), progress.last)
 			return ctx.Err()
-		case <-time.After(time.Second):
+		case <-end.idle():
+			end.onIdle(progress.processed)
 			break loop
 		}
 	}
 
-	// move messages back to source queue from temporary queue
-	return m.moveTempToSource(ctx, m.tempQueue, srcQueue)
+	return ackConfirmed(true)
 }
 
-func (m *QueueManager) moveTempToSource(ctx context.Context, tempQueue, srcQueue string) error {
-	messages, err := m.consumer.Consume(tempQueue)
+// processTempQueue moves messages from the temporary to the source queue until the end of the phase.
+// If the connection is lost, the error is returned without handling it, so that moving continues after reconnecting.
+func (m *QueueManager) processTempQueue(ctx context.Context, tempQueue, srcQueue string, end *phaseEnd, progress *phaseProgress, startTime time.Time) error {
+	messages, err := m.tempConsumer().Consume(tempQueue)
 	if err != nil {
+		m.recordErr("", err, amqp091.Delivery{})
 		return err
 	}
 
-	m.log.Info("moving messages from temporary to source queue")
-
-	startTime := time.Now()
-	var movedMessages int
-	var lastMovedMessage amqp091.Delivery
+	pipeline := newAckPipeline(m.publisher)
 
-	defer func() {
-		m.log.Info("moving messages from temporary to source queue finished",
-			slog.Int("movedMessages", movedMessages),
-			slog.Duration("duration", time.Since(startTime)),
-		)
-	}()
+	// unacknowledged messages are redelivered after reconnecting, those with unconfirmed publishings are moved again
+	connectionLost := func(err error) error {
+		unconfirmed := progress.connectionLost(pipeline)
+		if unconfirmed > 0 {
+			m.log.Warn("publishings of messages moved before the connection was lost were not confirmed. They will be moved again and may be duplicated in the source queue",
+				slog.Int("messages", unconfirmed),
+				slog.String("srcQueue", srcQueue),
+				slog.String("tempQueue", tempQueue),
+			)
+		}
+		return err
+	}
+	handleErr := func(step journal.Step, errMsg string, err error, msg amqp091.Delivery) error {
+		m.logMoveTempToSrcErr(errMsg, err, msg, srcQueue, tempQueue)
+		m.recordErr(step, err, msg)
+		errReject := msg.Reject(false)
+		if errReject != nil {
+			m.logMoveTempToSrcErr("error occurred while rejecting message", errReject, msg, srcQueue, tempQueue)
+		}
+		return err
+	}
+	// acknowledge temporary queue messages once they are confirmed in the source queue
+	ackConfirmed := func(wait bool) error {
+		msg, confirmed, err := pipeline.ack(wait, progress.acked)
+		if err == nil {
+			return nil
+		}
+		if !confirmed {
+			if m.connectionLost(err) {
+				return connectionLost(err)
+			}
+			return handleErr(journal.StepPublishSource, "error occurred while moving message from temporary to source queue", err, msg)
+		}
+		if err = m.ackErr(err); m.connectionLost(err) {
+			progress.handled.unacked(msg)
+			return connectionLost(err)
+		}
+		m.logMoveTempToSrcErr("error occurred while acknowledging message", err, msg, srcQueue, tempQueue)
+		m.recordErr(journal.StepAckTemp, err, msg)
+		return err
+	}
 
 loop:
-	for {
+	for !end.reached(progress.processed) {
 		select {
-		case msg := <-messages:
+		case msg, ok := <-messages:
+			if !ok {
+				err = m.tempConsumer().Err()
+				if m.connectionLost(err) {
+					return connectionLost(err)
+				}
+				m.logMoveTempToSrcErr("delivery channel closed while moving messages from temporary to source queue", err, progress.last, srcQueue, tempQueue)
+				m.recordErr(journal.StepConsume, err, progress.last)
+				return err
+			}
+			if progress.handled.isRedelivery(msg) {
+				// message was already moved before the connection was lost
+				pipeline.pushConfirmed(msg)
+				if err = ackConfirmed(false); err != nil {
+					return err
+				}
+				continue
+			}
 			// move/publish message back to the source queue
 			err = m.publisher.Publish(srcQueue, mappers.DeliveryPublishing(msg))
 			if err != nil {
-				m.logMoveTempToSrcErr("error occurred while moving message from temporary to source queue", err, msg, srcQueue, tempQueue)
-				errReject := msg.Reject(false)
-				if errReject != nil {
-					m.logMoveTempToSrcErr("error occurred while rejecting message", errReject, msg, srcQueue, tempQueue)
+				if m.connectionLost(err) {
+					// failed message is redelivered and moved again after reconnecting
+					return connectionLost(err)
 				}
-				return err
+				if ackErr := ackConfirmed(true); ackErr != nil {
+					return ackErr
+				}
+				return handleErr(journal.StepPublishSource, "error occurred while moving message from temporary to source queue", err, msg)
 			}
-			movedMessages++
-			err = msg.Ack(false)
-			if err != nil {
-				m.logMoveTempToSrcErr("error occurred while acknowledging message", err, msg, srcQueue, tempQueue)
+			progress.processed++
+			pipeline.push(msg, nil)
+			if err = ackConfirmed(false); err != nil {
 				return err
 			}
-			if movedMessages%1000 == 0 {
+			if progress.processed%1000 == 0 {
 				m.log.Info("moving messages from temporary to source queue progress",
-					slog.Int("movedMessages", movedMessages),
+					slog.Int("movedMessages", progress.processed),
 					slog.Duration("duration", time.Since(startTime)),
 				)
+				m.recordProgress(msg, func(op *journal.Operation) {
+					op.MovedMessages = progress.processed
+				})
+			}
+		case <-pipeline.done():
+			if err = ackConfirmed(false); err != nil {
+				return err
 			}
-			lastMovedMessage = msg
 		case <-ctx.Done():
+			if err = ackConfirmed(true); err != nil {
+				return err
+			}
 			m.log.Error("context cancelled while moving messages from temporary to source queue",
 				slog.Any("error", ctx.Err()),
-				slog.Any("lastMovedMessage", lastMovedMessage),
+				slog.Any("lastMovedMessage", progress.last),
 				slog.String("srcQueue", srcQueue),
 				slog.String("tempQueue", tempQueue),
 				slog.String("help", partialTempQueueMoveCtxCancelHelpMsg),
+				slog.String("operationID", m.journal.ID()),
 			)
+			m.recordErr("", ctx.Err(), progress.last)
 			return ctx.Err()
-		case <-time.After(time.Second):
+		case <-end.idle():
+			end.onIdle(progress.processed)
 			break loop
 		}
 	}
 
-	return nil
+	return ackConfirmed(true)
 }
 
-// endregion
+// insertRest inserts the remaining archived messages and waits for their confirmation, so that they are counted in the temporary queue.
+func (m *QueueManager) insertRest(srcQueue string) error {
+	if m.insertions == nil {
+		return nil
+	}
+	publisher := m.tempPublisher()
+	err := m.insertions.insertRest(func(archived amqp091.Publishing) error {
+		return publisher.Publish(m.tempQueue, archived)
+	})
+	if pipelined, ok := publisher.(messaging.PipelinedPublisher); ok && err == nil {
+		err = pipelined.Confirmation().Err()
+	}
+	if err != nil {
+		m.logMsgProcessingError("error occurred while inserting archived message", err, amqp091.Delivery{}, srcQueue)
+		m.recordErr(journal.StepPublishTemp, err, amqp091.Delivery{})
+	}
+	return err
+}
 
-// region Private
+// tempPublisher returns the publisher of messages kept in the temporary queue or the spool.
+func (m *QueueManager) tempPublisher() messaging.Publisher {
+	if m.spool != nil {
+		return m.spool
+	}
+	return m.publisher
+}
+
+// tempConsumer returns the consumer of messages kept in the temporary queue or the spool.
+func (m *QueueManager) tempConsumer() messaging.Consumer {
+	if m.spool != nil {
+		return m.spool
+	}
+	return m.consumer
+}
+
+// tempCounter returns the counter of messages kept in the temporary queue or the spool.
+func (m *QueueManager) tempCounter() messaging.MessageCounter {
+	if m.spool != nil {
+		return m.spool
+	}
+	return m.counter
+}
+
+// connectionLost reports whether the error is caused by a lost connection which can be re-established.
+func (m *QueueManager) connectionLost(err error) bool {
+	_, consumerReconnects := m.consumer.(messaging.Reconnector)
+	_, publisherReconnects := m.publisher.(messaging.Reconnector)
+	return consumerReconnects && publisherReconnects && m.insertions == nil && errors.Is(err, messaging.ErrConnectionLost)
+}
 
-func (m *QueueManager) handleMsgProcessingError(errMsg string, err error, msg amqp091.Delivery, srcQueue string) error {
+// reconnect re-establishes the lost connections of the consumer and publisher.
+func (m *QueueManager) reconnect(ctx context.Context, cause error, lastMsg amqp091.Delivery, srcQueue, help string) error {
+	m.log.Warn("connection lost, reconnecting",
+		slog.Any("error", cause),
+		slog.String("operationID", m.journal.ID()),
+	)
+
+	err := ctx.Err()
+	for _, reconnector := range []messaging.Reconnector{m.consumer.(messaging.Reconnector), m.publisher.(messaging.Reconnector)} {
+		if err == nil {
+			err = reconnector.Reconnect(ctx)
+		}
+	}
+	if err != nil {
+		m.log.Error("error occurred while reconnecting",
+			slog.Any("error", err),
+			slog.Any("cause", cause),
+			slog.Any("lastProcessedMessage", lastMsg),
+			slog.String("srcQueue", srcQueue),
+			slog.String("tempQueue", m.tempQueue),
+			slog.String("help", help),
+			slog.String("operationID", m.journal.ID()),
+		)
+		m.recordErr(journal.StepConsume, cause, lastMsg)
+		return err
+	}
+
+	m.log.Info("reconnected, continuing from the last processed message", slog.String("operationID", m.journal.ID()))
+	return nil
+}
+
+// ackErr returns the reason of the channel closure if acknowledging failed because the channel is closed.
+func (m *QueueManager) ackErr(err error) error {
+	if errors.Is(err, amqp091.ErrClosed) {
+		return m.consumer.Err()
+	}
+	return err
+}
+
+func (m *QueueManager) handleMsgProcessingError(step journal.Step, errMsg string, err error, msg amqp091.Delivery, srcQueue string) error {
 	m.logMsgProcessingError(errMsg, err, msg, srcQueue)
+	m.recordErr(step, err, msg)
 	errReject := msg.Reject(true)
 	if errReject != nil {
 		m.logMsgProcessingError("error occurred while rejecting message", errReject, msg, srcQueue)
@@ -208,6 +690,7 @@ func (m *QueueManager) logMsgProcessingError(errMsg string, err error, msg amqp0
 		slog.String("srcQueue", srcQueue),
 		slog.String("tempQueue", m.tempQueue),
 		slog.String("help", partialQueueManagementHelpMsg),
+		slog.String("operationID", m.journal.ID()),
 	)
 }
 
@@ -218,7 +701,32 @@ func (m *QueueManager) logMoveTempToSrcErr(errMsg string, err error, msg amqp091
 		slog.String("srcQueue", srcQueue),
 		slog.String("tempQueue", tempQueue),
 		slog.String("help", partialTempQueueMoveHelpMsg),
+		slog.String("operationID", m.journal.ID()),
 	)
 }
 
+func (m *QueueManager) record(update func(op *journal.Operation)) {
+	err := m.journal.Update(update)
+	if err != nil {
+		m.log.Warn("failed to update operation journal", slog.Any("error", err), slog.String("journal", m.journal.Path()))
+	}
+}
+
+func (m *QueueManager) recordProgress(lastMsg amqp091.Delivery, update func(op *journal.Operation)) {
+	m.record(func(op *journal.Operation) {
+		update(op)
+		op.LastProcessedMessage = journal.IdentityFromDelivery(lastMsg)
+	})
+}
+
+func (m *QueueManager) recordErr(step journal.Step, err error, lastMsg amqp091.Delivery) {
+	m.record(func(op *journal.Operation) {
+		op.Error = err.Error()
+		op.FailedStep = step
+		if lastMsg.DeliveryTag != 0 {
+			op.LastProcessedMessage = journal.IdentityFromDelivery(lastMsg)
+		}
+	})
+}
+
 // endregion
@@ -3,7 +3,10 @@ package managers_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -12,13 +15,17 @@ import (
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/mocks"
 	rmocks "github.com/happening-oss/rabbitmq-message-ops/internal/messaging/rabbitmq/mocks"
 	smocks "github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors/mocks"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/spool"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/tests/stubs"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/tests/util"
 
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
 	hmocks "github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers/mocks"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
 	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/managers"
 )
 
@@ -381,4 +388,426 @@ var _ = Describe("Queue manager", func() {
 			})
 		})
 	})
+	When("delivery channel is closed", func() {
+		BeforeEach(func() {
+			srcQueue := make(chan amqp091.Delivery)
+			close(srcQueue)
+			conMock.On(util.NameOf(conMock.Consume), "srcQueue").Return((<-chan amqp091.Delivery)(srcQueue), nil).Once()
+			conMock.On(util.NameOf(conMock.Err)).Return(errors.New("consumer cancelled")).Once()
+		})
+
+		It("throws the consumer error instead of treating the queue as drained", func() {
+			err := manager.Manage(context.Background(), "srcQueue")
+			Expect(err).To(MatchError("consumer cancelled"))
+		})
+	})
+
+	When("number of messages in the queue is known", func() {
+		var counterMock *mocks.MessageCounter
+		var srcMessages []amqp091.Delivery
+
+		BeforeEach(func() {
+			counterMock = mocks.NewMessageCounter(GinkgoT())
+			manager = managers.NewQueueManager(conMock, log, handler, pubMock, selectorMock, "tempQueue").
+				WithMessageCounter(counterMock).
+				WithIdleTimeout(time.Hour)
+
+			srcMessages = []amqp091.Delivery{
+				{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock},
+				{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock},
+			}
+			conMock.On(util.NameOf(conMock.Consume), "srcQueue").Return(initReadChannel(srcMessages), nil).Once()
+			conMock.On(util.NameOf(conMock.Consume), "tempQueue").Return(make(<-chan amqp091.Delivery), nil).Once()
+			selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(true, nil).Times(len(srcMessages))
+			handler.On(util.NameOf(handler.Handle), mock.Anything).Return(false, nil).Times(len(srcMessages))
+			ackMock.On(util.NameOf(ackMock.Ack), mock.Anything, false).Return(nil).Times(len(srcMessages))
+			counterMock.On(util.NameOf(counterMock.MessageCount), "tempQueue").Return(0, nil).Once()
+		})
+
+		It("ends each phase once all messages are processed, without waiting for the idle timeout", func() {
+			counterMock.On(util.NameOf(counterMock.MessageCount), "srcQueue").Return(len(srcMessages), nil).Once()
+
+			err := manager.Manage(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("ends the phase after the idle timeout if fewer messages arrive", func() {
+			manager.(*managers.QueueManager).WithIdleTimeout(time.Millisecond * 100)
+			counterMock.On(util.NameOf(counterMock.MessageCount), "srcQueue").Return(len(srcMessages)+1, nil).Once()
+
+			err := manager.Manage(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	When("publisher confirmations are pipelined", func() {
+		var pipelinedPubMock *mocks.PipelinedPublisher
+		var confirmationMock *mocks.Confirmation
+		var srcMessages []amqp091.Delivery
+
+		BeforeEach(func() {
+			pipelinedPubMock = mocks.NewPipelinedPublisher(GinkgoT())
+			confirmationMock = mocks.NewConfirmation(GinkgoT())
+			manager = managers.NewQueueManager(conMock, log, handler, pipelinedPubMock, selectorMock, "tempQueue")
+
+			srcMessages = []amqp091.Delivery{
+				{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock},
+				{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock},
+			}
+			conMock.On(util.NameOf(conMock.Consume), "srcQueue").Return(initReadChannel(srcMessages), nil).Once()
+			selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(false, nil)
+			pipelinedPubMock.On(util.NameOf(pipelinedPubMock.Publish), "tempQueue", mock.Anything).Return(nil)
+			pipelinedPubMock.On(util.NameOf(pipelinedPubMock.Confirmation)).Return(confirmationMock)
+			confirmationMock.On(util.NameOf(confirmationMock.Done)).Return(make(<-chan struct{})).Maybe()
+		})
+
+		When("publishings are confirmed", func() {
+			BeforeEach(func() {
+				confirmationMock.On(util.NameOf(confirmationMock.Err)).Return(nil)
+				for _, msg := range srcMessages {
+					ackMock.On(util.NameOf(ackMock.Ack), msg.DeliveryTag, false).Return(nil).Once()
+				}
+				conMock.On(util.NameOf(conMock.Consume), "tempQueue").Return(make(<-chan amqp091.Delivery), nil).Once()
+			})
+
+			It("acknowledges source queue messages after confirmation", func() {
+				err := manager.Manage(context.Background(), "srcQueue")
+				Expect(err).ToNot(HaveOccurred())
+				for _, msg := range srcMessages {
+					Expect(ackMock.AckedTags[msg.DeliveryTag]).To(BeTrue())
+				}
+			})
+
+			It("counts copies after confirmation", func() {
+				selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Unset()
+				selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(true, nil)
+				pipelinedPubMock.On(util.NameOf(pipelinedPubMock.Publish), "destQueue", mock.Anything).Return(nil).Twice()
+				copyHandler := handlers.NewCopyHandler(pipelinedPubMock, "destQueue")
+				manager = managers.NewQueueManager(conMock, log, copyHandler, pipelinedPubMock, selectorMock, "tempQueue")
+
+				err := manager.Manage(context.Background(), "srcQueue")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(copyHandler.Counts()).To(Equal(map[string]int{"destQueue": 2}))
+			})
+		})
+
+		When("publishings are negatively confirmed", func() {
+			BeforeEach(func() {
+				confirmationMock.On(util.NameOf(confirmationMock.Err)).Return(errors.New("negative confirmation"))
+				ackMock.On(util.NameOf(ackMock.Reject), srcMessages[0].DeliveryTag, true).Return(nil).Once()
+			})
+
+			It("returns error without acknowledging messages", func() {
+				err := manager.Manage(context.Background(), "srcQueue")
+				Expect(err).To(HaveOccurred())
+				for _, msg := range srcMessages {
+					Expect(ackMock.AckedTags[msg.DeliveryTag]).ToNot(BeTrue())
+				}
+			})
+
+			It("does not count unconfirmed copies", func() {
+				selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Unset()
+				selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(true, nil)
+				pipelinedPubMock.On(util.NameOf(pipelinedPubMock.Publish), "destQueue", mock.Anything).Return(nil)
+				copyHandler := handlers.NewCopyHandler(pipelinedPubMock, "destQueue")
+				manager = managers.NewQueueManager(conMock, log, copyHandler, pipelinedPubMock, selectorMock, "tempQueue")
+
+				err := manager.Manage(context.Background(), "srcQueue")
+				Expect(err).To(HaveOccurred())
+				Expect(copyHandler.Counts()).To(Equal(map[string]int{"destQueue": 0}))
+			})
+		})
+	})
+
+	When("operation is journaled", func() {
+		var opJournal *journal.Journal
+
+		BeforeEach(func() {
+			var err error
+			opJournal, err = journal.New(GinkgoT().TempDir(), journal.Operation{Command: "purge", SrcQueue: "srcQueue", TempQueue: "tempQueue"})
+			Expect(err).ToNot(HaveOccurred())
+			manager = managers.NewQueueManager(conMock, log, handler, pubMock, selectorMock, "tempQueue").WithJournal(opJournal)
+		})
+
+		It("records finished phase", func() {
+			conMock.On(util.NameOf(conMock.Consume), "srcQueue").Return(make(<-chan amqp091.Delivery), nil).Once()
+			conMock.On(util.NameOf(conMock.Consume), "tempQueue").Return(make(<-chan amqp091.Delivery), nil).Once()
+
+			err := manager.Manage(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opJournal.Operation().Phase).To(Equal(journal.PhaseFinished))
+		})
+
+		It("resumes from the temporary to source queue phase", func() {
+			Expect(opJournal.Update(func(op *journal.Operation) { op.Phase = journal.PhaseTempToSource })).To(Succeed())
+
+			tempMessages := []amqp091.Delivery{{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock}}
+			conMock.On(util.NameOf(conMock.Consume), "tempQueue").Return(initReadChannel(tempMessages), nil).Once()
+			pubMock.On(util.NameOf(pubMock.Publish), "srcQueue", mock.Anything).Return(nil).Once()
+			ackMock.On(util.NameOf(ackMock.Ack), tempMessages[0].DeliveryTag, false).Return(nil).Once()
+
+			err := manager.(*managers.QueueManager).Resume(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opJournal.Operation().Phase).To(Equal(journal.PhaseFinished))
+			Expect(opJournal.Operation().MovedMessages).To(Equal(1))
+		})
+
+		It("records error and last processed message", func() {
+			srcMessages := []amqp091.Delivery{{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock, MessageId: "msg-1"}}
+			conMock.On(util.NameOf(conMock.Consume), "srcQueue").Return(initReadChannel(srcMessages), nil).Once()
+			selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(false, errors.New("selector error")).Once()
+			ackMock.On(util.NameOf(ackMock.Reject), srcMessages[0].DeliveryTag, true).Return(nil).Once()
+
+			err := manager.Manage(context.Background(), "srcQueue")
+			Expect(err).To(HaveOccurred())
+			op := opJournal.Operation()
+			Expect(op.Phase).To(Equal(journal.PhaseSourceToTemp))
+			Expect(op.Error).To(Equal("selector error"))
+			Expect(op.FailedStep).To(Equal(journal.StepSelect))
+			Expect(op.ProcessedMessages).To(Equal(1))
+			Expect(op.LastProcessedMessage.Matches(srcMessages[0])).To(BeTrue())
+		})
+	})
+
+	When("connection is lost", func() {
+		var reconnectorMock *mocks.Reconnector
+		var counterMock *mocks.MessageCounter
+		var msg1 amqp091.Delivery
+
+		redelivered := func(msg amqp091.Delivery) amqp091.Delivery {
+			msg.DeliveryTag = sequenceNumber.Add(1)
+			msg.Redelivered = true
+			return msg
+		}
+		// consumeSrc delivers the lost messages until the connection is lost, and the other messages after reconnecting
+		consumeSrc := func(srcMessages int, lost []amqp091.Delivery, afterReconnect []amqp091.Delivery) {
+			lostQueue := make(chan amqp091.Delivery, len(lost))
+			for _, msg := range lost {
+				lostQueue <- msg
+			}
+			close(lostQueue)
+
+			counterMock.On(util.NameOf(counterMock.MessageCount), "srcQueue").Return(srcMessages, nil).Once()
+			conMock.On(util.NameOf(conMock.Consume), "srcQueue").Return((<-chan amqp091.Delivery)(lostQueue), nil).Once()
+			conMock.On(util.NameOf(conMock.Err)).Return(fmt.Errorf("consumer: %w: connection reset", messaging.ErrConnectionLost)).Once()
+			conMock.On(util.NameOf(conMock.Consume), "srcQueue").Return(initReadChannel(afterReconnect), nil).Once()
+		}
+
+		BeforeEach(func() {
+			reconnectorMock = mocks.NewReconnector(GinkgoT())
+			counterMock = mocks.NewMessageCounter(GinkgoT())
+			msg1 = amqp091.Delivery{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock, MessageId: "msg-1", Body: []byte("body1")}
+
+			counterMock.On(util.NameOf(counterMock.MessageCount), "tempQueue").Return(0, nil).Once()
+			reconnectorMock.On(util.NameOf(reconnectorMock.Reconnect), mock.Anything).Return(nil).Twice()
+			conMock.On(util.NameOf(conMock.Consume), "tempQueue").Return(make(<-chan amqp091.Delivery), nil).Once()
+		})
+
+		It("reconnects and only acknowledges redelivered messages which were processed, but not acknowledged", func() {
+			pipelinedPubMock := mocks.NewPipelinedPublisher(GinkgoT())
+			confirmationMock := mocks.NewConfirmation(GinkgoT())
+			manager = managers.NewQueueManager(
+				&reconnectingConsumer{Consumer: conMock, Reconnector: reconnectorMock},
+				log, handler,
+				&reconnectingPipelinedPublisher{PipelinedPublisher: pipelinedPubMock, Reconnector: reconnectorMock},
+				selectorMock, "tempQueue",
+			).WithMessageCounter(counterMock).WithIdleTimeout(time.Hour)
+
+			// publishing of msg-0 is confirmed, but msg-0 is not acknowledged before the connection is lost
+			msg0 := amqp091.Delivery{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock, MessageId: "msg-0", Body: []byte("body0")}
+			consumeSrc(2, []amqp091.Delivery{msg0}, []amqp091.Delivery{redelivered(msg0), msg1})
+			selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(false, nil).Twice()
+			pipelinedPubMock.On(util.NameOf(pipelinedPubMock.Publish), "tempQueue", mock.Anything).Return(nil).Twice()
+			pipelinedPubMock.On(util.NameOf(pipelinedPubMock.Confirmation)).Return(confirmationMock)
+			confirmationMock.On(util.NameOf(confirmationMock.Done)).Return(make(<-chan struct{})).Maybe()
+			confirmationMock.On(util.NameOf(confirmationMock.Err)).Return(nil)
+			ackMock.On(util.NameOf(ackMock.Ack), mock.Anything, false).Return(nil).Twice()
+
+			err := manager.Manage(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ackMock.AckedTags).To(HaveLen(2))
+			Expect(ackMock.AckedTags).ToNot(HaveKey(msg0.DeliveryTag))
+		})
+
+		It("processes a redelivered message identical to a message acknowledged before the connection was lost", func() {
+			manager = managers.NewQueueManager(
+				&reconnectingConsumer{Consumer: conMock, Reconnector: reconnectorMock},
+				log, handler,
+				&reconnectingPublisher{Publisher: pubMock, Reconnector: reconnectorMock},
+				selectorMock, "tempQueue",
+			).WithMessageCounter(counterMock).WithIdleTimeout(time.Hour)
+
+			// msg-a is acknowledged, identical msg-b is prefetched, but not delivered before the connection is lost
+			msgA := amqp091.Delivery{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock, MessageId: "msg-dup", Body: []byte("body")}
+			msgB := msgA
+			msgB.DeliveryTag = sequenceNumber.Add(1)
+			consumeSrc(3, []amqp091.Delivery{msgA}, []amqp091.Delivery{redelivered(msgB), msg1})
+			selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(false, nil).Times(3)
+			pubMock.On(util.NameOf(pubMock.Publish), "tempQueue", mock.Anything).Return(nil).Times(3)
+			ackMock.On(util.NameOf(ackMock.Ack), mock.Anything, false).Return(nil).Times(3)
+
+			err := manager.Manage(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ackMock.AckedTags).To(HaveLen(3))
+		})
+	})
+	When("source is a priority queue", func() {
+		var broker *fakeBroker
+
+		BeforeEach(func() {
+			broker = newFakeBroker()
+			broker.Declare("srcQueue", 5)
+			// effective priority of msg-e is capped to the max priority of the queue
+			for _, msg := range []amqp091.Publishing{
+				{MessageId: "msg-a"},
+				{MessageId: "msg-b", Priority: 5},
+				{MessageId: "msg-c"},
+				{MessageId: "msg-d", Priority: 3},
+				{MessageId: "msg-e", Priority: 9},
+			} {
+				Expect(broker.Publish("srcQueue", msg)).To(Succeed())
+			}
+			selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(false, nil)
+		})
+
+		DescribeTable("keeps the FIFO order of messages with the same priority",
+			func(tempQueueMaxPriority uint8) {
+				broker.Declare("tempQueue", tempQueueMaxPriority)
+				manager = managers.NewQueueManager(broker, log, handler, broker, selectorMock, "tempQueue").
+					WithMessageCounter(broker).
+					WithMaxPriority(5)
+
+				err := manager.Manage(context.Background(), "srcQueue")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(broker.Drain("srcQueue")).To(Equal([]string{"msg-b", "msg-e", "msg-d", "msg-a", "msg-c"}))
+			},
+			Entry("with a temporary priority queue", uint8(5)),
+			Entry("with a temporary queue without priorities", uint8(0)),
+		)
+	})
+
+	When("messages are kept in the spool", func() {
+		It("moves kept messages back to the source queue in order without a temporary queue", func() {
+			broker := newFakeBroker()
+			broker.Declare("srcQueue", 0)
+			for _, id := range []string{"msg-a", "msg-b", "msg-c"} {
+				Expect(broker.Publish("srcQueue", amqp091.Publishing{MessageId: id})).To(Succeed())
+			}
+			selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(func(msg amqp091.Delivery) (bool, error) {
+				return msg.MessageId == "msg-b", nil
+			})
+			handler.On(util.NameOf(handler.Handle), mock.Anything).Return(false, nil).Once()
+
+			path := filepath.Join(GinkgoT().TempDir(), "operation.spool")
+			s, err := spool.Open(path)
+			Expect(err).ToNot(HaveOccurred())
+			DeferCleanup(s.Close)
+			opJournal, err := journal.New(GinkgoT().TempDir(), journal.Operation{Command: "purge", SrcQueue: "srcQueue"})
+			Expect(err).ToNot(HaveOccurred())
+
+			manager = managers.NewQueueManager(broker, log, handler, broker, selectorMock, "").
+				WithMessageCounter(broker).
+				WithJournal(opJournal).
+				WithSpool(s)
+
+			err = manager.Manage(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(broker.Drain("srcQueue")).To(Equal([]string{"msg-a", "msg-c"}))
+			Expect(s.Empty()).To(BeTrue())
+			Expect(opJournal.Operation().Spool).To(Equal(path))
+			Expect(opJournal.Operation().TempQueue).To(BeEmpty())
+		})
+	})
+
+	When("stop is requested", func() {
+		It("moves remaining messages through the temporary queue without processing them", func() {
+			broker := newFakeBroker()
+			broker.Declare("srcQueue", 0)
+			broker.Declare("tempQueue", 0)
+			for _, id := range []string{"msg-a", "msg-b", "msg-c"} {
+				Expect(broker.Publish("srcQueue", amqp091.Publishing{MessageId: id})).To(Succeed())
+			}
+			stop := make(chan struct{})
+			// stop is requested while the first message is processed
+			selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(true, nil).Run(func(mock.Arguments) { close(stop) }).Once()
+			handler.On(util.NameOf(handler.Handle), mock.Anything).Return(false, nil).Once()
+
+			manager = managers.NewQueueManager(broker, log, handler, broker, selectorMock, "tempQueue").
+				WithMessageCounter(broker).
+				WithStop(stop)
+
+			err := manager.Manage(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(broker.Drain("srcQueue")).To(Equal([]string{"msg-b", "msg-c"}))
+		})
+	})
+
+	When("handler edits a kept message", func() {
+		It("keeps the edited message in place of the original one", func() {
+			broker := newFakeBroker()
+			broker.Declare("srcQueue", 0)
+			broker.Declare("tempQueue", 0)
+			for _, id := range []string{"msg-a", "msg-b", "msg-c"} {
+				Expect(broker.Publish("srcQueue", amqp091.Publishing{MessageId: id})).To(Succeed())
+			}
+			selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(func(msg amqp091.Delivery) (bool, error) {
+				return msg.MessageId == "msg-b", nil
+			})
+			editing := handlers.NewInteractiveHandler(handler, "purge", strings.NewReader("e\nk\n"), GinkgoWriter).
+				WithEditor(func(msg amqp091.Publishing) (amqp091.Publishing, bool, error) {
+					msg.MessageId = "msg-b-edited"
+					return msg, true, nil
+				})
+
+			manager = managers.NewQueueManager(broker, log, editing, broker, selectorMock, "tempQueue").
+				WithMessageCounter(broker)
+
+			err := manager.Manage(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(broker.Drain("srcQueue")).To(Equal([]string{"msg-a", "msg-b-edited", "msg-c"}))
+		})
+	})
+
+	When("removed messages are archived", func() {
+		It("inserts archived messages back at their original positions", func() {
+			broker := newFakeBroker()
+			broker.Declare("srcQueue", 0)
+			broker.Declare("tempQueue", 0)
+			for _, id := range []string{"msg-a", "msg-b", "msg-c", "msg-d", "msg-e"} {
+				Expect(broker.Publish("srcQueue", amqp091.Publishing{MessageId: id})).To(Succeed())
+			}
+			selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(func(msg amqp091.Delivery) (bool, error) {
+				return msg.MessageId == "msg-b" || msg.MessageId == "msg-d" || msg.MessageId == "msg-e", nil
+			}).Times(5)
+			handler.On(util.NameOf(handler.Handle), mock.Anything).Return(false, nil).Times(3)
+
+			archive, err := spool.Open(filepath.Join(GinkgoT().TempDir(), "operation.archive"))
+			Expect(err).ToNot(HaveOccurred())
+			DeferCleanup(archive.Close)
+
+			err = managers.NewQueueManager(broker, log, handler, broker, selectorMock, "tempQueue").
+				WithMessageCounter(broker).
+				WithArchive(archive).
+				Manage(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(broker.Drain("srcQueue")).To(Equal([]string{"msg-a", "msg-c"}))
+
+			// undo the removal of the drained queue with the remaining messages
+			for _, id := range []string{"msg-a", "msg-c"} {
+				Expect(broker.Publish("srcQueue", amqp091.Publishing{MessageId: id})).To(Succeed())
+			}
+			reader, err := archive.Reader()
+			Expect(err).ToNot(HaveOccurred())
+			DeferCleanup(reader.Close)
+			noSelector := smocks.NewSelector(GinkgoT())
+			noSelector.On(util.NameOf(noSelector.IsSelected), mock.Anything).Return(false, nil).Twice()
+
+			err = managers.NewQueueManager(broker, log, handler, broker, noSelector, "tempQueue").
+				WithMessageCounter(broker).
+				WithInsertions(reader).
+				Manage(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(broker.Drain("srcQueue")).To(Equal([]string{"msg-a", "msg-b", "msg-c", "msg-d", "msg-e"}))
+		})
+	})
 })
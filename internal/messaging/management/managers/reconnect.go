@@ -0,0 +1,52 @@
+package managers
+
+import (
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+)
+
+// handledMessages remembers messages which were handled, but not acknowledged, before the connection was lost.
+// Their redeliveries are only acknowledged, so that they are not published again.
+// Acknowledged messages are not remembered: an identical redelivered message is another copy (e.g. prefetched but not yet handled),
+// which must be processed.
+type handledMessages struct {
+	redelivered map[handledKey]int
+}
+
+// handledKey identifies a handled message by its message ID and body.
+type handledKey struct {
+	messageID  string
+	bodySHA256 string
+}
+
+// unacked remembers the message whose publishings were confirmed, but which was not acknowledged before the connection was lost.
+func (h *handledMessages) unacked(msg amqp091.Delivery) {
+	if h.redelivered == nil {
+		h.redelivered = make(map[handledKey]int)
+	}
+	h.redelivered[newHandledKey(msg)]++
+}
+
+// isRedelivery reports whether the message was already handled before the connection was lost.
+// Requeued messages are redelivered first, so nothing is expected once a message which was not redelivered arrives.
+func (h *handledMessages) isRedelivery(msg amqp091.Delivery) bool {
+	if len(h.redelivered) == 0 {
+		return false
+	}
+	if !msg.Redelivered {
+		h.redelivered = nil
+		return false
+	}
+	key := newHandledKey(msg)
+	if h.redelivered[key] == 0 {
+		return false
+	}
+	h.redelivered[key]--
+	return true
+}
+
+func newHandledKey(msg amqp091.Delivery) handledKey {
+	identity := journal.IdentityFromDelivery(msg)
+	return handledKey{messageID: identity.MessageID, bodySHA256: identity.BodySHA256}
+}
@@ -22,15 +22,47 @@ type StreamManager struct {
 	handler   handlers.MessageHandler
 	publisher messaging.Publisher
 	selector  selectors.Selector
+
+	counter     messaging.MessageCounter
+	idleTimeout time.Duration
+	stop        <-chan struct{}
+	replay      *Replay
 }
 
 func NewStreamManager(consumer messaging.Consumer, log *slog.Logger, handler handlers.MessageHandler, publisher messaging.Publisher, selector selectors.Selector) *StreamManager {
 	return &StreamManager{consumer: consumer, log: log, handler: handler, publisher: publisher, selector: selector}
 }
 
+// WithMessageCounter makes the manager end once all messages present in the stream at the start are processed.
+// Idle timeout is then only used as a safety net.
+func (m *StreamManager) WithMessageCounter(counter messaging.MessageCounter) *StreamManager {
+	m.counter = counter
+	return m
+}
+
+// WithIdleTimeout sets how long the manager waits for the next message before ending.
+// Zero selects a default, which is longer if the number of messages is known.
+func (m *StreamManager) WithIdleTimeout(idleTimeout time.Duration) *StreamManager {
+	m.idleTimeout = idleTimeout
+	return m
+}
+
+// WithStop makes the manager stop taking messages from the source stream once stop is closed.
+func (m *StreamManager) WithStop(stop <-chan struct{}) *StreamManager {
+	m.stop = stop
+	return m
+}
+
+// WithReplay makes the manager handle selected messages with their original gaps, see Replay.
+func (m *StreamManager) WithReplay(replay Replay) *StreamManager {
+	m.replay = &replay
+	return m
+}
+
 // region Public
 
 func (m *StreamManager) Manage(ctx context.Context, srcStream string) error {
+	end := newPhaseEnd(m.log, m.counter, m.idleTimeout, srcStream)
 	messages, err := m.consumer.Consume(srcStream)
 	if err != nil {
 		return err
@@ -41,6 +73,11 @@ func (m *StreamManager) Manage(ctx context.Context, srcStream string) error {
 	startTime := time.Now()
 	var processedMessages, selectedMessages int
 	var lastProcessedMessage amqp091.Delivery
+	pipeline := newAckPipeline(m.publisher)
+	var pacer *replayPacer
+	if m.replay != nil {
+		pacer = newReplayPacer(*m.replay)
+	}
 
 	defer func() {
 		m.log.Info("processing source stream finished",
@@ -50,35 +87,84 @@ func (m *StreamManager) Manage(ctx context.Context, srcStream string) error {
 		)
 	}()
 
+	// acknowledge source stream messages once their publishings are confirmed
+	ackConfirmed := func(wait bool) error {
+		msg, confirmed, err := pipeline.ack(wait, func(msg amqp091.Delivery) { lastProcessedMessage = msg })
+		if err == nil {
+			return nil
+		}
+		if !confirmed {
+			return m.handleSrcMsgErr("error occurred while confirming published messages", err, msg, srcStream)
+		}
+		m.logHandleSrcMsgErr("error occurred while acknowledging message", err, msg, srcStream)
+		return err
+	}
+	handleErr := func(errMsg string, err error, msg amqp091.Delivery) error {
+		if ackErr := ackConfirmed(true); ackErr != nil {
+			return ackErr
+		}
+		return m.handleSrcMsgErr(errMsg, err, msg, srcStream)
+	}
+
 loop:
-	for {
+	for !end.reached(processedMessages) {
+		// stop takes precedence over messages which are already waiting
 		select {
-		case msg := <-messages:
+		case <-m.stop:
+			m.log.Warn("stop requested, remaining source stream messages are left unprocessed", slog.Int("processedMessages", processedMessages))
+			break loop
+		default:
+		}
+
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				err = m.consumer.Err()
+				m.log.Error("delivery channel closed while processing source stream",
+					slog.Any("error", err),
+					slog.Any("lastProcessedMessage", lastProcessedMessage),
+					slog.String("srcStream", srcStream),
+					slog.String("help", partialStreamManagementHelpMsg),
+				)
+				return err
+			}
 			processedMessages++
 			selected, err := m.selector.IsSelected(msg)
 			if err != nil {
-				m.logHandleSrcMsgErr("error occurred while checking if message is selected", err, msg, srcStream)
-				errReject := msg.Reject(true)
-				if errReject != nil {
-					m.logHandleSrcMsgErr("error occurred while rejecting message", errReject, msg, srcStream)
+				return handleErr("error occurred while checking if message is selected", err, msg)
+			}
+			if selected && pacer != nil {
+				// wait until the message is due, unless the operation is stopped or cancelled meanwhile
+				select {
+				case <-time.After(time.Until(pacer.due(pacer.timestamp(msg), time.Now()))):
+				case <-m.stop:
+					// the waiting message is left unprocessed together with the remaining ones
+					processedMessages--
+					m.log.Warn("stop requested, remaining source stream messages are left unprocessed", slog.Int("processedMessages", processedMessages))
+					break loop
+				case <-ctx.Done():
+					if err = ackConfirmed(true); err != nil {
+						return err
+					}
+					m.log.Error("context cancelled while replaying source stream",
+						slog.Any("error", ctx.Err()),
+						slog.Any("lastProcessedMessage", lastProcessedMessage),
+						slog.String("srcStream", srcStream),
+						slog.String("help", partialStreamManagementHelpMsg),
+					)
+					return ctx.Err()
 				}
-				return err
 			}
+			var onConfirmed func()
 			if selected {
 				selectedMessages++
-				_, err = m.handler.Handle(msg)
+				_, onConfirmed, err = handle(m.handler, msg)
 				if err != nil {
-					m.logHandleSrcMsgErr("error occurred while handling message", err, msg, srcStream)
-					errReject := msg.Reject(true)
-					if errReject != nil {
-						m.logHandleSrcMsgErr("error occurred while rejecting message", errReject, msg, srcStream)
-					}
-					return err
+					return handleErr("error occurred while handling message", err, msg)
 				}
 			}
-			err = msg.Ack(false) // purge/remove message from the source queue
-			if err != nil {
-				m.logHandleSrcMsgErr("error occurred while acknowledging message", err, msg, srcStream)
+			pipeline.push(msg, onConfirmed) // purge/remove message from the source queue once confirmed
+			if err = ackConfirmed(false); err != nil {
 				return err
 			}
 			if processedMessages%1000 == 0 {
@@ -88,8 +174,14 @@ loop:
 					slog.Duration("duration", time.Since(startTime)),
 				)
 			}
-			lastProcessedMessage = msg
+		case <-pipeline.done():
+			if err = ackConfirmed(false); err != nil {
+				return err
+			}
 		case <-ctx.Done():
+			if err = ackConfirmed(true); err != nil {
+				return err
+			}
 			m.log.Error("context cancelled while processing source stream",
 				slog.Any("error", ctx.Err()),
 				slog.Any("lastProcessedMessage", lastProcessedMessage),
@@ -97,18 +189,28 @@ loop:
 				slog.String("help", partialStreamManagementHelpMsg),
 			)
 			return ctx.Err()
-		case <-time.After(time.Second):
+		case <-end.idle():
+			end.onIdle(processedMessages)
 			break loop
 		}
 	}
 
-	return nil
+	return ackConfirmed(true)
 }
 
 // endregion
 
 // region Private
 
+func (m *StreamManager) handleSrcMsgErr(errMsg string, err error, msg amqp091.Delivery, srcStream string) error {
+	m.logHandleSrcMsgErr(errMsg, err, msg, srcStream)
+	errReject := msg.Reject(true)
+	if errReject != nil {
+		m.logHandleSrcMsgErr("error occurred while rejecting message", errReject, msg, srcStream)
+	}
+	return err
+}
+
 func (m *StreamManager) logHandleSrcMsgErr(errMsg string, err error, msg amqp091.Delivery, srcStream string) {
 	m.log.Error(errMsg,
 		slog.Any("error", err),
@@ -119,3 +221,77 @@ func (m *StreamManager) logHandleSrcMsgErr(errMsg string, err error, msg amqp091
 }
 
 // endregion
+
+// region Structs
+
+// Replay sets how messages are replayed with the gaps between their timestamps (timestamp property).
+// The timestamp property has a resolution of one second, so gaps shorter than a second are lost, unless the messages carry
+// their time in milliseconds in the TimestampHeader. Messages without a timestamp are handled without waiting.
+type Replay struct {
+	// Speed multiplies the replay speed, e.g. 10 replays ten times faster than the original. Defaults to 1.
+	Speed float64
+	// MaxGap limits the gap between two messages after applying the speed, 0 means no limit.
+	MaxGap time.Duration
+	// TimestampHeader is the header with the time of the message in milliseconds since the epoch, e.g. timestamp_in_ms set by
+	// the message timestamp plugin of RabbitMQ. If a message carries it, it is used instead of the timestamp property.
+	TimestampHeader string
+}
+
+// replayPacer schedules messages relative to the first replayed message, so that the time spent handling messages does not add up.
+// If handling falls behind the schedule, messages are handled without waiting until it catches up.
+type replayPacer struct {
+	replay Replay
+	last   time.Time
+	next   time.Time
+}
+
+func newReplayPacer(replay Replay) *replayPacer {
+	if replay.Speed <= 0 {
+		replay.Speed = 1
+	}
+	return &replayPacer{replay: replay}
+}
+
+// timestamp returns the time of the message, read from the timestamp header if the message carries it.
+func (p *replayPacer) timestamp(msg amqp091.Delivery) time.Time {
+	if p.replay.TimestampHeader == "" {
+		return msg.Timestamp
+	}
+	switch millis := msg.Headers[p.replay.TimestampHeader].(type) {
+	case int64:
+		return time.UnixMilli(millis)
+	case int32:
+		return time.UnixMilli(int64(millis))
+	case int:
+		return time.UnixMilli(int64(millis))
+	case float64:
+		return time.UnixMilli(int64(millis))
+	default:
+		return msg.Timestamp
+	}
+}
+
+// due returns the time at which the message with the timestamp is due.
+func (p *replayPacer) due(timestamp, now time.Time) time.Time {
+	if timestamp.IsZero() {
+		return now
+	}
+	if p.last.IsZero() {
+		p.last, p.next = timestamp, now
+		return now
+	}
+
+	if !timestamp.After(p.last) {
+		// messages with timestamps out of order are not delayed
+		return p.next
+	}
+	gap := time.Duration(float64(timestamp.Sub(p.last)) / p.replay.Speed)
+	if p.replay.MaxGap > 0 {
+		gap = min(gap, p.replay.MaxGap)
+	}
+	p.last = timestamp
+	p.next = p.next.Add(gap)
+	return p.next
+}
+
+// endregion
@@ -227,4 +227,65 @@ var _ = Describe("Stream manager", func() {
 			Expect(err).To(HaveOccurred())
 		})
 	})
+
+	When("replaying messages", func() {
+		var srcMessages []amqp091.Delivery
+		var handled []time.Time
+
+		BeforeEach(func() {
+			start := time.Now()
+			// the timestamp header differs from the timestamp property, so that the tests tell which one is used
+			srcMessages = []amqp091.Delivery{
+				{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock, Timestamp: start, Headers: amqp091.Table{"timestamp_in_ms": start.UnixMilli()}},
+				{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock, Timestamp: start.Add(200 * time.Millisecond), Headers: amqp091.Table{"timestamp_in_ms": start.Add(400 * time.Millisecond).UnixMilli()}},
+				{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock, Timestamp: start.Add(time.Hour), Headers: amqp091.Table{"timestamp_in_ms": start.Add(time.Hour).UnixMilli()}},
+			}
+			conMock.On(util.NameOf(conMock.Consume), "srcQueue").Return(initReadChannel(srcMessages), nil).Once()
+			counterMock := mocks.NewMessageCounter(GinkgoT())
+			counterMock.On(util.NameOf(counterMock.MessageCount), "srcQueue").Return(len(srcMessages), nil).Once()
+			selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(true, nil)
+			ackMock.On(util.NameOf(ackMock.Ack), mock.Anything, false).Return(nil).Maybe()
+
+			handled = nil
+			handler.On(util.NameOf(handler.Handle), mock.Anything).Return(true, nil).Run(func(mock.Arguments) {
+				handled = append(handled, time.Now())
+			}).Maybe()
+
+			manager = managers.NewStreamManager(conMock, log, handler, pubMock, selectorMock).
+				WithMessageCounter(counterMock).
+				WithReplay(managers.Replay{Speed: 2, MaxGap: 300 * time.Millisecond})
+		})
+
+		It("handles messages with their original gaps divided by speed and limited by max gap", func() {
+			err := manager.Manage(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(handled).To(HaveLen(3))
+			Expect(handled[1].Sub(handled[0])).To(BeNumerically("~", 100*time.Millisecond, 50*time.Millisecond))
+			Expect(handled[2].Sub(handled[1])).To(BeNumerically("~", 300*time.Millisecond, 50*time.Millisecond))
+		})
+
+		It("stops waiting for the next message once stop is requested", func() {
+			stop := make(chan struct{})
+			manager = manager.(*managers.StreamManager).WithStop(stop)
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				close(stop)
+			}()
+
+			err := manager.Manage(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handled).To(HaveLen(1))
+		})
+
+		It("uses the time in milliseconds from the timestamp header instead of the timestamp property", func() {
+			manager = manager.(*managers.StreamManager).WithReplay(managers.Replay{Speed: 2, MaxGap: 300 * time.Millisecond, TimestampHeader: "timestamp_in_ms"})
+
+			err := manager.Manage(context.Background(), "srcQueue")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(handled).To(HaveLen(3))
+			Expect(handled[1].Sub(handled[0])).To(BeNumerically("~", 200*time.Millisecond, 50*time.Millisecond))
+		})
+	})
 })
@@ -0,0 +1,87 @@
+package managers
+
+import (
+	"errors"
+	"io"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/spool"
+)
+
+// insertions re-inserts archived messages at their original positions in the source queue.
+// All methods are safe to call on nil insertions, in which case they do nothing.
+type insertions struct {
+	reader   *spool.Reader
+	next     *spool.Record
+	last     int
+	inserted int
+}
+
+func newInsertions(reader *spool.Reader) *insertions {
+	if reader == nil {
+		return nil
+	}
+	return &insertions{reader: reader, last: -1}
+}
+
+// insertBefore publishes the archived messages whose original position is not after the position of the next source queue message.
+// The position of the next message is the number of processed source queue messages plus the number of inserted messages.
+func (i *insertions) insertBefore(processed int, publish func(msg amqp091.Publishing) error) error {
+	if i == nil {
+		return nil
+	}
+	for {
+		record, err := i.peek()
+		if err != nil || record == nil || record.Position > processed+i.inserted {
+			return err
+		}
+		if err = i.insert(publish); err != nil {
+			return err
+		}
+	}
+}
+
+// insertRest publishes all remaining archived messages, since they were at the end of the source queue.
+func (i *insertions) insertRest(publish func(msg amqp091.Publishing) error) error {
+	if i == nil {
+		return nil
+	}
+	for {
+		record, err := i.peek()
+		if err != nil || record == nil {
+			return err
+		}
+		if err = i.insert(publish); err != nil {
+			return err
+		}
+	}
+}
+
+func (i *insertions) peek() (*spool.Record, error) {
+	for i.next == nil {
+		record, err := i.reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// message processed again after reconnecting is archived twice
+		if record.Position <= i.last {
+			continue
+		}
+		i.next = &record
+	}
+	return i.next, nil
+}
+
+func (i *insertions) insert(publish func(msg amqp091.Publishing) error) error {
+	if err := publish(i.next.Publishing); err != nil {
+		return err
+	}
+	i.last = i.next.Position
+	i.inserted++
+	i.next = nil
+	return nil
+}
@@ -0,0 +1,259 @@
+package managers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
+)
+
+const (
+	partialUnorderedManagementHelpMsg = `Source queue has potentially been partially managed. Messages which were not acknowledged are requeued to the source queue by the broker.
+If publishing to the destination queue (move/copy commands) succeeded, but acknowledging the message failed, please manually remove the duplicated message from the source or destination queue.
+The command can be run again, since the order of messages is not preserved anyway.`
+)
+
+// UnorderedManager processes the source queue in a single pass, without the temporary queue.
+// Messages which are not removed from the source queue are held unacknowledged until the end of the pass and then requeued at once,
+// so that they are not delivered again during the pass. Requeueing them in batches is not possible, since requeued messages return to
+// their original position at the head of the queue and would be delivered again right away. Therefore, the number of unacknowledged
+// messages must not be limited: a consumer implementing messaging.Prefetcher is switched to unlimited prefetch before consuming.
+// Classic queues requeue messages to their original position, but the order of messages is not guaranteed.
+type UnorderedManager struct {
+	consumer  messaging.Consumer
+	log       *slog.Logger
+	handler   handlers.MessageHandler
+	publisher messaging.Publisher
+	selector  selectors.Selector
+
+	counter     messaging.MessageCounter
+	idleTimeout time.Duration
+	stop        <-chan struct{}
+}
+
+func NewUnorderedManager(consumer messaging.Consumer, log *slog.Logger, handler handlers.MessageHandler, publisher messaging.Publisher, selector selectors.Selector) *UnorderedManager {
+	return &UnorderedManager{consumer: consumer, log: log, handler: handler, publisher: publisher, selector: selector}
+}
+
+// WithMessageCounter makes the manager end once all messages present in the queue at the start are processed.
+// Idle timeout is then only used as a safety net.
+func (m *UnorderedManager) WithMessageCounter(counter messaging.MessageCounter) *UnorderedManager {
+	m.counter = counter
+	return m
+}
+
+// WithIdleTimeout sets how long the manager waits for the next message before ending.
+// Zero selects a default, which is longer if the number of messages is known.
+func (m *UnorderedManager) WithIdleTimeout(idleTimeout time.Duration) *UnorderedManager {
+	m.idleTimeout = idleTimeout
+	return m
+}
+
+// WithStop makes the manager stop taking messages from the source queue once stop is closed.
+func (m *UnorderedManager) WithStop(stop <-chan struct{}) *UnorderedManager {
+	m.stop = stop
+	return m
+}
+
+// region Public
+
+func (m *UnorderedManager) Manage(ctx context.Context, srcQueue string) error {
+	if prefetcher, ok := m.consumer.(messaging.Prefetcher); ok {
+		// with limited prefetch, the pass would stall once the held messages fill the prefetch window
+		if err := prefetcher.Prefetch(0); err != nil {
+			return err
+		}
+	}
+	end := newPhaseEnd(m.log, m.counter, m.idleTimeout, srcQueue)
+	messages, err := m.consumer.Consume(srcQueue)
+	if err != nil {
+		return err
+	}
+
+	m.log.Info("processing source queue without preserving the order")
+
+	startTime := time.Now()
+	var processedMessages, selectedMessages, heldMessages int
+	var lastProcessedMessage, lastHeldMessage amqp091.Delivery
+	// heldConfirmed are called once the messages published while handling the held messages are confirmed
+	var heldConfirmed []func()
+	pipeline := newAckPipeline(m.publisher)
+
+	defer func() {
+		m.log.Info("processing source queue finished",
+			slog.Int("processedMessages", processedMessages),
+			slog.Int("selectedMessages", selectedMessages),
+			slog.Int("requeuedMessages", heldMessages),
+			slog.Duration("duration", time.Since(startTime)),
+		)
+	}()
+
+	// requeue held messages at once, once nothing else is left unacknowledged
+	requeueHeld := func() error {
+		if heldMessages == 0 {
+			return nil
+		}
+		if err := lastHeldMessage.Nack(true, true); err != nil {
+			m.logHandleSrcMsgErr("error occurred while requeueing messages", err, lastHeldMessage, srcQueue)
+			return err
+		}
+		return nil
+	}
+	// acknowledge (purge/remove) source queue messages once their publishings are confirmed
+	ackConfirmed := func(wait bool) error {
+		msg, confirmed, err := pipeline.ack(wait, func(msg amqp091.Delivery) { lastProcessedMessage = msg })
+		if err == nil {
+			return nil
+		}
+		if !confirmed {
+			// remaining deliveries are requeued with the held messages
+			pipeline.drain(func(amqp091.Delivery, bool) {})
+			err = m.handleSrcMsgErr("error occurred while confirming published messages", err, msg, srcQueue)
+			_ = requeueHeld()
+			return err
+		}
+		m.logHandleSrcMsgErr("error occurred while acknowledging message", err, msg, srcQueue)
+		return err
+	}
+	handleErr := func(errMsg string, err error, msg amqp091.Delivery) error {
+		if ackErr := ackConfirmed(true); ackErr != nil {
+			return ackErr
+		}
+		err = m.handleSrcMsgErr(errMsg, err, msg, srcQueue)
+		_ = requeueHeld()
+		return err
+	}
+
+loop:
+	for !end.reached(processedMessages) {
+		// stop takes precedence over messages which are already waiting
+		select {
+		case <-m.stop:
+			m.log.Warn("stop requested, remaining source queue messages are left unprocessed", slog.Int("processedMessages", processedMessages))
+			break loop
+		default:
+		}
+
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				err = m.consumer.Err()
+				m.log.Error("delivery channel closed while processing source queue",
+					slog.Any("error", err),
+					slog.Any("lastProcessedMessage", lastProcessedMessage),
+					slog.String("srcQueue", srcQueue),
+					slog.String("help", partialUnorderedManagementHelpMsg),
+				)
+				return err
+			}
+			processedMessages++
+			selected, err := m.selector.IsSelected(msg)
+			if err != nil {
+				return handleErr("error occurred while checking if message is selected", err, msg)
+			}
+
+			requeue := true
+			var onConfirmed func()
+			if selected {
+				selectedMessages++
+				requeue, onConfirmed, err = handle(m.handler, msg)
+				if err != nil {
+					return handleErr("error occurred while handling message", err, msg)
+				}
+			}
+
+			if requeue {
+				// keep message unacknowledged, so that it is not delivered again during the pass
+				heldMessages++
+				lastHeldMessage = msg
+				if onConfirmed != nil {
+					heldConfirmed = append(heldConfirmed, onConfirmed)
+				}
+			} else {
+				pipeline.push(msg, onConfirmed) // purge/remove message from the source queue once confirmed
+			}
+			if err = ackConfirmed(false); err != nil {
+				return err
+			}
+			if processedMessages%1000 == 0 {
+				m.log.Info("processing source queue progress",
+					slog.Int("processedMessages", processedMessages),
+					slog.Int("selectedMessages", selectedMessages),
+					slog.Duration("duration", time.Since(startTime)),
+				)
+			}
+		case <-pipeline.done():
+			if err = ackConfirmed(false); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			if err = ackConfirmed(true); err != nil {
+				return err
+			}
+			m.log.Error("context cancelled while processing source queue",
+				slog.Any("error", ctx.Err()),
+				slog.Any("lastProcessedMessage", lastProcessedMessage),
+				slog.String("srcQueue", srcQueue),
+				slog.String("help", partialUnorderedManagementHelpMsg),
+			)
+			_ = requeueHeld()
+			return ctx.Err()
+		case <-end.idle():
+			end.onIdle(processedMessages)
+			break loop
+		}
+	}
+
+	if err = ackConfirmed(true); err != nil {
+		return err
+	}
+	if err = m.confirmHeld(heldConfirmed); err != nil {
+		m.logHandleSrcMsgErr("error occurred while confirming messages published while handling kept messages", err, lastHeldMessage, srcQueue)
+		_ = requeueHeld()
+		return err
+	}
+	return requeueHeld()
+}
+
+// endregion
+
+// region Private
+
+// confirmHeld waits for the confirmation of messages published after the last removed message, e.g. while handling kept messages,
+// and then calls the confirmation functions of the held messages.
+func (m *UnorderedManager) confirmHeld(heldConfirmed []func()) error {
+	if pipelined, ok := m.publisher.(messaging.PipelinedPublisher); ok {
+		if err := pipelined.Confirmation().Err(); err != nil {
+			return err
+		}
+	}
+	for _, confirmed := range heldConfirmed {
+		confirmed()
+	}
+	return nil
+}
+
+func (m *UnorderedManager) handleSrcMsgErr(errMsg string, err error, msg amqp091.Delivery, srcQueue string) error {
+	m.logHandleSrcMsgErr(errMsg, err, msg, srcQueue)
+	errReject := msg.Reject(true)
+	if errReject != nil {
+		m.logHandleSrcMsgErr("error occurred while rejecting message", errReject, msg, srcQueue)
+	}
+	return err
+}
+
+func (m *UnorderedManager) logHandleSrcMsgErr(errMsg string, err error, msg amqp091.Delivery, srcQueue string) {
+	m.log.Error(errMsg,
+		slog.Any("error", err),
+		slog.Any("msg", msg),
+		slog.String("srcQueue", srcQueue),
+		slog.String("help", partialUnorderedManagementHelpMsg),
+	)
+}
+
+// endregion
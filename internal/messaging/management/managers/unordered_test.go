@@ -0,0 +1,118 @@
+package managers_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/mocks"
+	rmocks "github.com/happening-oss/rabbitmq-message-ops/internal/messaging/rabbitmq/mocks"
+	smocks "github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors/mocks"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/tests/stubs"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/tests/util"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	hmocks "github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers/mocks"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/managers"
+)
+
+var _ = Describe("Unordered manager", func() {
+	var conMock *mocks.Consumer
+	var pubMock *mocks.Publisher
+	var counterMock *mocks.MessageCounter
+	var handler *hmocks.MessageHandler
+	var selectorMock *smocks.Selector
+	var ackMock *rmocks.Acknowledger
+
+	var manager managers.Manager
+
+	var sequenceNumber atomic.Uint64
+	var srcMessages []amqp091.Delivery
+
+	BeforeEach(func() {
+		conMock = mocks.NewConsumer(GinkgoT())
+		pubMock = mocks.NewPublisher(GinkgoT())
+		counterMock = mocks.NewMessageCounter(GinkgoT())
+		handler = hmocks.NewMessageHandler(GinkgoT())
+		selectorMock = smocks.NewSelector(GinkgoT())
+		ackMock = rmocks.NewAcknowledger(GinkgoT())
+
+		manager = managers.NewUnorderedManager(conMock, slog.New(stubs.NewHandler()), handler, pubMock, selectorMock).WithMessageCounter(counterMock)
+
+		srcMessages = []amqp091.Delivery{
+			{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock, MessageId: "msg-1"},
+			{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock, MessageId: "msg-2"},
+			{DeliveryTag: sequenceNumber.Add(1), Acknowledger: ackMock, MessageId: "msg-3"},
+		}
+		counterMock.On(util.NameOf(counterMock.MessageCount), "srcQueue").Return(len(srcMessages), nil).Once()
+		conMock.On(util.NameOf(conMock.Consume), "srcQueue").Return(initReadChannel(srcMessages), nil).Once()
+	})
+
+	It("acknowledges removed messages and requeues the others at once in a single pass", func() {
+		selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(func(msg amqp091.Delivery) (bool, error) {
+			return msg.MessageId != "msg-2", nil
+		})
+		handler.On(util.NameOf(handler.Handle), mock.Anything).Return(false, nil).Twice()
+		ackMock.On(util.NameOf(ackMock.Ack), mock.Anything, false).Return(nil).Twice()
+		ackMock.On(util.NameOf(ackMock.Nack), srcMessages[1].DeliveryTag, true, true).Return(nil).Once()
+
+		err := manager.Manage(context.Background(), "srcQueue")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ackMock.AckedTags).To(HaveKey(srcMessages[0].DeliveryTag))
+		Expect(ackMock.AckedTags).To(HaveKey(srcMessages[2].DeliveryTag))
+		Expect(ackMock.AckedTags).ToNot(HaveKey(srcMessages[1].DeliveryTag))
+	})
+
+	It("does not limit the number of prefetched messages, since kept messages are held until the end of the pass", func() {
+		prefetcherMock := mocks.NewPrefetcher(GinkgoT())
+		prefetcherMock.On(util.NameOf(prefetcherMock.Prefetch), 0).Return(nil).Once()
+		consumer := prefetchingConsumer{Consumer: conMock, Prefetcher: prefetcherMock}
+		manager = managers.NewUnorderedManager(consumer, slog.New(stubs.NewHandler()), handler, pubMock, selectorMock).WithMessageCounter(counterMock)
+		selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(false, nil).Times(len(srcMessages))
+		ackMock.On(util.NameOf(ackMock.Nack), srcMessages[2].DeliveryTag, true, true).Return(nil).Once()
+
+		err := manager.Manage(context.Background(), "srcQueue")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("returns error if prefetch cannot be set", func() {
+		counterMock.On(util.NameOf(counterMock.MessageCount), "srcQueue").Unset()
+		conMock.On(util.NameOf(conMock.Consume), "srcQueue").Unset()
+		prefetcherMock := mocks.NewPrefetcher(GinkgoT())
+		prefetcherMock.On(util.NameOf(prefetcherMock.Prefetch), 0).Return(errors.New("qos error")).Once()
+		consumer := prefetchingConsumer{Consumer: conMock, Prefetcher: prefetcherMock}
+		manager = managers.NewUnorderedManager(consumer, slog.New(stubs.NewHandler()), handler, pubMock, selectorMock).WithMessageCounter(counterMock)
+
+		err := manager.Manage(context.Background(), "srcQueue")
+		Expect(err).To(MatchError("qos error"))
+	})
+
+	It("counts copies of held messages once the pass is confirmed", func() {
+		selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(true, nil).Times(len(srcMessages))
+		pubMock.On(util.NameOf(pubMock.Publish), "destQueue", mock.Anything).Return(nil).Times(len(srcMessages))
+		ackMock.On(util.NameOf(ackMock.Nack), srcMessages[2].DeliveryTag, true, true).Return(nil).Once()
+		copyHandler := handlers.NewCopyHandler(pubMock, "destQueue")
+		manager = managers.NewUnorderedManager(conMock, slog.New(stubs.NewHandler()), copyHandler, pubMock, selectorMock).WithMessageCounter(counterMock)
+
+		err := manager.Manage(context.Background(), "srcQueue")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(copyHandler.Counts()).To(Equal(map[string]int{"destQueue": 3}))
+	})
+
+	It("requeues held messages after the failed message is rejected", func() {
+		selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(false, nil).Once()
+		selectorMock.On(util.NameOf(selectorMock.IsSelected), mock.Anything).Return(false, errors.New("selector error")).Once()
+		ackMock.On(util.NameOf(ackMock.Reject), srcMessages[1].DeliveryTag, true).Return(nil).Once()
+		ackMock.On(util.NameOf(ackMock.Nack), srcMessages[0].DeliveryTag, true, true).Return(nil).Once()
+
+		err := manager.Manage(context.Background(), "srcQueue")
+		Expect(err).To(MatchError("selector error"))
+		Expect(ackMock.AckedTags).To(BeEmpty())
+	})
+})
@@ -0,0 +1,186 @@
+package mappers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	BodyFormatJSON   = "json"
+	BodyFormatText   = "text"
+	BodyFormatBase64 = "base64"
+)
+
+// PublishingDocument encodes the message properties and body as an indented JSON document which can be edited by the user.
+// JSON bodies are embedded as JSON, valid UTF-8 bodies as text and other bodies as base64.
+func PublishingDocument(msg amqp091.Publishing) ([]byte, error) {
+	doc := Document{
+		Headers:         msg.Headers,
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		DeliveryMode:    msg.DeliveryMode,
+		Priority:        msg.Priority,
+		CorrelationID:   msg.CorrelationId,
+		ReplyTo:         msg.ReplyTo,
+		Expiration:      msg.Expiration,
+		MessageID:       msg.MessageId,
+		Type:            msg.Type,
+		UserID:          msg.UserId,
+		AppID:           msg.AppId,
+	}
+	if !msg.Timestamp.IsZero() {
+		doc.Timestamp = msg.Timestamp.Format(time.RFC3339)
+	}
+
+	var err error
+	switch {
+	case len(msg.Body) > 0 && json.Valid(msg.Body):
+		doc.BodyFormat = BodyFormatJSON
+		doc.Body = msg.Body
+	case utf8.Valid(msg.Body):
+		doc.BodyFormat = BodyFormatText
+		doc.Body, err = json.Marshal(string(msg.Body))
+	default:
+		doc.BodyFormat = BodyFormatBase64
+		doc.Body, err = json.Marshal(base64.StdEncoding.EncodeToString(msg.Body))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// ParsePublishingDocument decodes the message encoded by PublishingDocument.
+// JSON bodies are compacted and numbers in headers are decoded as integers if possible.
+func ParsePublishingDocument(data []byte) (amqp091.Publishing, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	var doc Document
+	if err := decoder.Decode(&doc); err != nil {
+		return amqp091.Publishing{}, fmt.Errorf("invalid message document: %w", err)
+	}
+
+	msg := amqp091.Publishing{
+		Headers:         table(doc.Headers),
+		ContentType:     doc.ContentType,
+		ContentEncoding: doc.ContentEncoding,
+		DeliveryMode:    doc.DeliveryMode,
+		Priority:        doc.Priority,
+		CorrelationId:   doc.CorrelationID,
+		ReplyTo:         doc.ReplyTo,
+		Expiration:      doc.Expiration,
+		MessageId:       doc.MessageID,
+		Type:            doc.Type,
+		UserId:          doc.UserID,
+		AppId:           doc.AppID,
+	}
+	if doc.Timestamp != "" {
+		timestamp, err := time.Parse(time.RFC3339, doc.Timestamp)
+		if err != nil {
+			return amqp091.Publishing{}, fmt.Errorf("invalid message timestamp: %w", err)
+		}
+		msg.Timestamp = timestamp
+	}
+
+	var err error
+	msg.Body, err = documentBody(doc)
+	if err != nil {
+		return amqp091.Publishing{}, err
+	}
+	if err = msg.Headers.Validate(); err != nil {
+		return amqp091.Publishing{}, fmt.Errorf("invalid message headers: %w", err)
+	}
+	return msg, nil
+}
+
+// region Helpers
+
+func documentBody(doc Document) ([]byte, error) {
+	if len(doc.Body) == 0 {
+		return nil, nil
+	}
+	switch doc.BodyFormat {
+	case BodyFormatJSON:
+		var body bytes.Buffer
+		if err := json.Compact(&body, doc.Body); err != nil {
+			return nil, err
+		}
+		return body.Bytes(), nil
+	case BodyFormatText, BodyFormatBase64:
+		var text string
+		if err := json.Unmarshal(doc.Body, &text); err != nil {
+			return nil, fmt.Errorf("%v body must be a string: %w", doc.BodyFormat, err)
+		}
+		if doc.BodyFormat == BodyFormatText {
+			return []byte(text), nil
+		}
+		return base64.StdEncoding.DecodeString(text)
+	default:
+		return nil, errors.New("unsupported body format: " + doc.BodyFormat)
+	}
+}
+
+// table converts decoded JSON values to the types supported in AMQP tables.
+func table(values map[string]interface{}) amqp091.Table {
+	if values == nil {
+		return nil
+	}
+	result := make(amqp091.Table, len(values))
+	for key, value := range values {
+		result[key] = tableValue(value)
+	}
+	return result
+}
+
+func tableValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if number, err := v.Int64(); err == nil {
+			return number
+		}
+		number, _ := v.Float64()
+		return number
+	case map[string]interface{}:
+		return table(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = tableValue(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// endregion
+
+// region Structs
+
+// Document is the editable representation of a message.
+type Document struct {
+	Headers         map[string]interface{} `json:"headers,omitempty"`
+	ContentType     string                 `json:"contentType,omitempty"`
+	ContentEncoding string                 `json:"contentEncoding,omitempty"`
+	DeliveryMode    uint8                  `json:"deliveryMode,omitempty"`
+	Priority        uint8                  `json:"priority,omitempty"`
+	CorrelationID   string                 `json:"correlationID,omitempty"`
+	ReplyTo         string                 `json:"replyTo,omitempty"`
+	Expiration      string                 `json:"expiration,omitempty"`
+	MessageID       string                 `json:"messageID,omitempty"`
+	Timestamp       string                 `json:"timestamp,omitempty"`
+	Type            string                 `json:"type,omitempty"`
+	UserID          string                 `json:"userID,omitempty"`
+	AppID           string                 `json:"appID,omitempty"`
+	BodyFormat      string                 `json:"bodyFormat"`
+	Body            json.RawMessage        `json:"body,omitempty"`
+}
+
+// endregion
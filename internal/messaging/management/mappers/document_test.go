@@ -0,0 +1,50 @@
+package mappers_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
+)
+
+func TestMappers(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mappers")
+}
+
+var _ = Describe("Publishing document", func() {
+	DescribeTable("round trips the message",
+		func(body []byte, format string) {
+			msg := amqp091.Publishing{
+				Headers:     amqp091.Table{"count": int64(3), "ratio": 0.5, "nested": amqp091.Table{"key": "value"}},
+				ContentType: "application/json",
+				MessageId:   "msg-1",
+				Priority:    2,
+				Timestamp:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				Body:        body,
+			}
+
+			doc, err := mappers.PublishingDocument(msg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(doc)).To(ContainSubstring(`"bodyFormat": "` + format + `"`))
+
+			parsed, err := mappers.ParsePublishingDocument(doc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.Timestamp.Equal(msg.Timestamp)).To(BeTrue())
+			parsed.Timestamp = msg.Timestamp
+			Expect(parsed).To(Equal(msg))
+		},
+		Entry("JSON body", []byte(`{"key":[1,2]}`), mappers.BodyFormatJSON),
+		Entry("text body", []byte("plain text"), mappers.BodyFormatText),
+		Entry("binary body", []byte{0xff, 0x00}, mappers.BodyFormatBase64),
+	)
+
+	It("rejects unknown fields", func() {
+		_, err := mappers.ParsePublishingDocument([]byte(`{"bodyFormat":"text","unknown":1}`))
+		Expect(err).To(HaveOccurred())
+	})
+})
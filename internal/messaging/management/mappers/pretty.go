@@ -0,0 +1,18 @@
+package mappers
+
+import (
+	"encoding/json"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
+)
+
+// PrettyDelivery encodes the message as indented JSON for reading. JSON body is shown decoded instead of as an escaped string.
+func PrettyDelivery(msg amqp091.Delivery) ([]byte, error) {
+	subset := selectors.SubsetFromDelivery(msg)
+	if len(msg.Body) > 0 && json.Valid(msg.Body) {
+		subset.Body = json.RawMessage(msg.Body)
+	}
+	return json.MarshalIndent(subset, "", "  ")
+}
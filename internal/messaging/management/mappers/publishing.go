@@ -20,3 +20,22 @@ func DeliveryPublishing(msg amqp091.Delivery) amqp091.Publishing {
 		Body:            msg.Body,
 	}
 }
+
+// PublishedDelivery returns the delivery with the properties and body replaced by the publishing.
+func PublishedDelivery(msg amqp091.Delivery, publishing amqp091.Publishing) amqp091.Delivery {
+	msg.Headers = publishing.Headers
+	msg.ContentType = publishing.ContentType
+	msg.ContentEncoding = publishing.ContentEncoding
+	msg.DeliveryMode = publishing.DeliveryMode
+	msg.Priority = publishing.Priority
+	msg.CorrelationId = publishing.CorrelationId
+	msg.ReplyTo = publishing.ReplyTo
+	msg.Expiration = publishing.Expiration
+	msg.MessageId = publishing.MessageId
+	msg.Timestamp = publishing.Timestamp
+	msg.Type = publishing.Type
+	msg.UserId = publishing.UserId
+	msg.AppId = publishing.AppId
+	msg.Body = publishing.Body
+	return msg
+}
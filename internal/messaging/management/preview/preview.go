@@ -0,0 +1,94 @@
+package preview
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// maxExampleIDs is the number of example message IDs kept by the preview.
+	maxExampleIDs = 5
+	// noType is reported for selected messages without a type.
+	noType = "<none>"
+)
+
+// Preview summarizes the messages selected from the front of the source queue.
+// If all messages in the queue are sampled, the preview is exact, otherwise it is only an estimate.
+type Preview struct {
+	Total    int // number of messages in the queue
+	Sampled  int
+	Selected int
+
+	types      map[string]int
+	exampleIDs []string
+}
+
+func NewPreview(total int) *Preview {
+	return &Preview{Total: total, types: make(map[string]int)}
+}
+
+// region Public
+
+// Add adds the sampled message to the preview.
+func (p *Preview) Add(msg amqp091.Delivery, selected bool) {
+	p.Sampled++
+	if !selected {
+		return
+	}
+	p.Selected++
+
+	msgType := msg.Type
+	if msgType == "" {
+		msgType = noType
+	}
+	p.types[msgType]++
+	if msg.MessageId != "" && len(p.exampleIDs) < maxExampleIDs {
+		p.exampleIDs = append(p.exampleIDs, msg.MessageId)
+	}
+}
+
+// Exact reports whether all messages in the queue were sampled.
+func (p *Preview) Exact() bool {
+	return p.Sampled >= p.Total
+}
+
+// Estimate returns the estimated number of selected messages in the whole queue.
+func (p *Preview) Estimate() int {
+	if p.Exact() || p.Sampled == 0 {
+		return p.Selected
+	}
+	return int(float64(p.Selected) / float64(p.Sampled) * float64(p.Total))
+}
+
+// TopTypes returns up to n most frequent types of the selected messages, ordered by count and name.
+func (p *Preview) TopTypes(n int) []TypeCount {
+	types := make([]TypeCount, 0, len(p.types))
+	for msgType, count := range p.types {
+		types = append(types, TypeCount{Type: msgType, Count: count})
+	}
+	slices.SortFunc(types, func(a, b TypeCount) int {
+		if a.Count != b.Count {
+			return cmp.Compare(b.Count, a.Count)
+		}
+		return cmp.Compare(a.Type, b.Type)
+	})
+	return types[:min(n, len(types))]
+}
+
+// ExampleIDs returns the message IDs of the first selected messages.
+func (p *Preview) ExampleIDs() []string {
+	return p.exampleIDs
+}
+
+// endregion
+
+// region Structs
+
+type TypeCount struct {
+	Type  string
+	Count int
+}
+
+// endregion
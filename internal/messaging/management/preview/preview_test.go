@@ -0,0 +1,45 @@
+package preview_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/preview"
+)
+
+func TestPreview(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Preview")
+}
+
+var _ = Describe("Preview", func() {
+	It("counts selected messages by type", func() {
+		p := preview.NewPreview(4)
+		p.Add(amqp091.Delivery{MessageId: "msg-1", Type: "b"}, true)
+		p.Add(amqp091.Delivery{MessageId: "msg-2", Type: "a"}, true)
+		p.Add(amqp091.Delivery{MessageId: "msg-3", Type: "c"}, false)
+		p.Add(amqp091.Delivery{MessageId: "msg-4", Type: "a"}, true)
+
+		Expect(p.Exact()).To(BeTrue())
+		Expect(p.Estimate()).To(Equal(3))
+		Expect(p.TopTypes(5)).To(Equal([]preview.TypeCount{{Type: "a", Count: 2}, {Type: "b", Count: 1}}))
+		Expect(p.TopTypes(1)).To(Equal([]preview.TypeCount{{Type: "a", Count: 2}}))
+		Expect(p.ExampleIDs()).To(Equal([]string{"msg-1", "msg-2", "msg-4"}))
+	})
+
+	It("estimates selected messages from the sample", func() {
+		p := preview.NewPreview(100)
+		p.Add(amqp091.Delivery{}, true)
+		p.Add(amqp091.Delivery{}, false)
+		p.Add(amqp091.Delivery{}, true)
+		p.Add(amqp091.Delivery{}, false)
+
+		Expect(p.Exact()).To(BeFalse())
+		Expect(p.Estimate()).To(Equal(50))
+		Expect(p.TopTypes(5)).To(Equal([]preview.TypeCount{{Type: "<none>", Count: 2}}))
+		Expect(p.ExampleIDs()).To(BeEmpty())
+	})
+})
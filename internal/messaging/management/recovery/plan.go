@@ -0,0 +1,117 @@
+package recovery
+
+import (
+	"fmt"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+)
+
+type Action string
+
+const (
+	ActionRemoveSourceHead Action = "removeSourceHead"
+	ActionRemoveTempHead   Action = "removeTempHead"
+	ActionMoveSourceToTemp Action = "moveSourceToTemp"
+	ActionMoveTempToSource Action = "moveTempToSource"
+)
+
+// NewPlan creates a plan which brings the queues of the failed operation back into a consistent state.
+// Remaining source queue messages are not processed by the original command, they are only kept behind the already processed messages.
+func NewPlan(state State) Plan {
+	op := state.Operation
+	last := op.LastProcessedMessage
+	var plan Plan
+
+	switch op.Phase {
+	case journal.PhaseFinished:
+		return plan
+	case journal.PhaseTempToSource:
+		tempHeadIsLast := state.TempHead != nil && last.Matches(*state.TempHead)
+		switch {
+		case op.FailedStep == journal.StepAckTemp && tempHeadIsLast:
+			plan.add(ActionRemoveTempHead, fmt.Sprintf("Remove duplicated message %v from the front of the temporary queue %v (publishing to the source queue succeeded, but acknowledging failed).", describe(*state.TempHead), op.TempQueue))
+		case op.FailedStep == journal.StepPublishSource && !tempHeadIsLast:
+			plan.warn(fmt.Sprintf("Last processed message %v is not at the front of the temporary queue. It may have been dropped, please check the source queue %v.", describeIdentity(last), op.SrcQueue))
+		}
+	default:
+		sourceHeadIsLast := state.SourceHead != nil && last.Matches(*state.SourceHead)
+		switch {
+		case op.FailedStep == journal.StepAck && sourceHeadIsLast:
+			plan.add(ActionRemoveSourceHead, fmt.Sprintf("Remove duplicated message %v from the front of the source queue %v (message was already processed, but acknowledging failed).", describe(*state.SourceHead), op.SrcQueue))
+		case op.FailedStep == journal.StepConfirm:
+			plan.warn(fmt.Sprintf("Confirming publishings of message %v failed. Messages handled after it may be duplicated in the temporary or destination queue.", describeIdentity(last)))
+		case op.FailedStep == journal.StepHandle && op.Args["destination"] != "":
+			plan.warn(fmt.Sprintf("Handling of message %v failed. Please check if it was published to the destination queue %v anyway.", describeIdentity(last), op.Args["destination"]))
+		case op.FailedStep == journal.StepArchive && op.Args["destination"] != "":
+			plan.warn(fmt.Sprintf("Archiving of message %v failed after it was published to the destination queue %v. It will be duplicated there if the operation is resumed.", describeIdentity(last), op.Args["destination"]))
+		case op.FailedStep != "" && op.FailedStep != journal.StepConsume && last != nil && !sourceHeadIsLast:
+			plan.warn(fmt.Sprintf("Last processed message %v is not at the front of the source queue %v. Please move it to the front manually before continuing.", describeIdentity(last), op.SrcQueue))
+		}
+		if state.SourceHead != nil {
+			plan.add(ActionMoveSourceToTemp, fmt.Sprintf("Move remaining messages from the source queue %v to the temporary queue %v, without processing them.", op.SrcQueue, op.TempQueue))
+		}
+	}
+
+	plan.add(ActionMoveTempToSource, fmt.Sprintf("Move all messages from the temporary queue %v to the source queue %v, preserving the order.", op.TempQueue, op.SrcQueue))
+	return plan
+}
+
+// region Structs
+
+type State struct {
+	// Operation describes the failed operation. Usually read from the operation journal.
+	Operation journal.Operation
+	// SourceHead is the message at the front of the source queue, nil if the queue is empty.
+	SourceHead *amqp091.Delivery
+	// TempHead is the message at the front of the temporary queue, nil if the queue is empty.
+	TempHead *amqp091.Delivery
+}
+
+type Step struct {
+	Action      Action
+	Description string
+}
+
+type Plan struct {
+	Steps    []Step
+	Warnings []string
+}
+
+func (p *Plan) Contains(action Action) bool {
+	for _, step := range p.Steps {
+		if step.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Plan) add(action Action, description string) {
+	p.Steps = append(p.Steps, Step{Action: action, Description: description})
+}
+
+func (p *Plan) warn(warning string) {
+	p.Warnings = append(p.Warnings, warning)
+}
+
+// endregion
+
+// region Helpers
+
+func describe(msg amqp091.Delivery) string {
+	return describeIdentity(journal.IdentityFromDelivery(msg))
+}
+
+func describeIdentity(identity *journal.MessageIdentity) string {
+	if identity == nil {
+		return "(unknown)"
+	}
+	if identity.MessageID != "" {
+		return fmt.Sprintf("(messageID: %v)", identity.MessageID)
+	}
+	return fmt.Sprintf("(bodySHA256: %v)", identity.BodySHA256)
+}
+
+// endregion
@@ -0,0 +1,97 @@
+package recovery_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/journal"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/recovery"
+)
+
+func TestRecovery(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Recovery")
+}
+
+var _ = Describe("Recovery plan", func() {
+	lastMsg := amqp091.Delivery{MessageId: "msg-1", Body: []byte("body1")}
+	otherMsg := amqp091.Delivery{MessageId: "msg-2", Body: []byte("body2")}
+
+	actions := func(plan recovery.Plan) []recovery.Action {
+		var result []recovery.Action
+		for _, step := range plan.Steps {
+			result = append(result, step.Action)
+		}
+		return result
+	}
+
+	When("operation is finished", func() {
+		It("has nothing to do", func() {
+			plan := recovery.NewPlan(recovery.State{Operation: journal.Operation{Phase: journal.PhaseFinished}})
+			Expect(plan.Steps).To(BeEmpty())
+		})
+	})
+
+	When("processing source queue failed", func() {
+		It("removes duplicate left by failed acknowledgment", func() {
+			plan := recovery.NewPlan(recovery.State{
+				Operation:  journal.Operation{Phase: journal.PhaseSourceToTemp, FailedStep: journal.StepAck, LastProcessedMessage: journal.IdentityFromDelivery(lastMsg)},
+				SourceHead: &lastMsg,
+			})
+			Expect(actions(plan)).To(Equal([]recovery.Action{recovery.ActionRemoveSourceHead, recovery.ActionMoveSourceToTemp, recovery.ActionMoveTempToSource}))
+			Expect(plan.Warnings).To(BeEmpty())
+		})
+
+		It("keeps message requeued after failed handling", func() {
+			plan := recovery.NewPlan(recovery.State{
+				Operation:  journal.Operation{Phase: journal.PhaseSourceToTemp, FailedStep: journal.StepPublishTemp, LastProcessedMessage: journal.IdentityFromDelivery(lastMsg)},
+				SourceHead: &lastMsg,
+			})
+			Expect(actions(plan)).To(Equal([]recovery.Action{recovery.ActionMoveSourceToTemp, recovery.ActionMoveTempToSource}))
+		})
+
+		It("warns when last processed message is not at the front", func() {
+			plan := recovery.NewPlan(recovery.State{
+				Operation:  journal.Operation{Phase: journal.PhaseSourceToTemp, FailedStep: journal.StepAck, LastProcessedMessage: journal.IdentityFromDelivery(lastMsg)},
+				SourceHead: &otherMsg,
+			})
+			Expect(plan.Contains(recovery.ActionRemoveSourceHead)).To(BeFalse())
+			Expect(plan.Warnings).To(HaveLen(1))
+		})
+
+		It("only moves temporary queue messages when source queue is empty", func() {
+			plan := recovery.NewPlan(recovery.State{Operation: journal.Operation{Phase: journal.PhaseSourceToTemp}})
+			Expect(actions(plan)).To(Equal([]recovery.Action{recovery.ActionMoveTempToSource}))
+		})
+
+		It("matches last processed message by message ID", func() {
+			plan := recovery.NewPlan(recovery.State{
+				Operation:  journal.Operation{Phase: journal.PhaseSourceToTemp, FailedStep: journal.StepAck, LastProcessedMessage: &journal.MessageIdentity{MessageID: "msg-1"}},
+				SourceHead: &lastMsg,
+			})
+			Expect(plan.Contains(recovery.ActionRemoveSourceHead)).To(BeTrue())
+		})
+	})
+
+	When("moving temporary queue messages failed", func() {
+		It("removes duplicate left by failed acknowledgment", func() {
+			plan := recovery.NewPlan(recovery.State{
+				Operation: journal.Operation{Phase: journal.PhaseTempToSource, FailedStep: journal.StepAckTemp, LastProcessedMessage: journal.IdentityFromDelivery(lastMsg)},
+				TempHead:  &lastMsg,
+			})
+			Expect(actions(plan)).To(Equal([]recovery.Action{recovery.ActionRemoveTempHead, recovery.ActionMoveTempToSource}))
+		})
+
+		It("warns when rejected message is missing", func() {
+			plan := recovery.NewPlan(recovery.State{
+				Operation: journal.Operation{Phase: journal.PhaseTempToSource, FailedStep: journal.StepPublishSource, LastProcessedMessage: journal.IdentityFromDelivery(lastMsg)},
+				TempHead:  &otherMsg,
+			})
+			Expect(actions(plan)).To(Equal([]recovery.Action{recovery.ActionMoveTempToSource}))
+			Expect(plan.Warnings).To(HaveLen(1))
+		})
+	})
+})
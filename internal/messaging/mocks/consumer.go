@@ -53,6 +53,20 @@ func (_m *Consumer) Consume(queue string) (<-chan amqp091.Delivery, error) {
 	return r0, r1
 }
 
+// Err provides a mock function with given fields:
+func (_m *Consumer) Err() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewConsumer creates a new instance of Consumer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewConsumer(t interface {
@@ -0,0 +1,217 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/mappers"
+)
+
+const (
+	exportAlloc  = "alloc"
+	exportFree   = "free"
+	exportSelect = "select"
+	exportHandle = "handle"
+)
+
+// callTimeout limits a single call of the plugin, so that a plugin stuck in a loop does not block the operation.
+const callTimeout = 10 * time.Second
+
+// Plugin is a sandboxed WebAssembly module which selects and/or handles messages.
+//
+// ABI: the module exports its memory and
+//   - alloc(size i32) i32 allocating a buffer in which the host writes the message,
+//   - free(ptr i32, size i32) (optional) releasing buffers allocated by alloc and buffers returned by handle,
+//   - select(ptr i32, size i32) i32 (optional) returning 1 if the message is selected, 0 if not and a negative value on error,
+//   - handle(ptr i32, size i32) i64 (optional) returning the verdict (see handlers.Verdict) as JSON, packed as ptr<<32 | size.
+//
+// The message is passed as a single line JSON document of its properties, headers and body (see mappers.PublishingDocument).
+// WASI is available without filesystem, network, environment variables and arguments, stdout and stderr of the plugin are written to stderr.
+// Reactor modules are initialized by their _initialize export, the _start export is not called.
+type Plugin struct {
+	runtime wazero.Runtime
+	module  api.Module
+	alloc   api.Function
+	free    api.Function
+	selectF api.Function
+	handleF api.Function
+	mu      sync.Mutex
+}
+
+// Load compiles and instantiates the plugin module from the file.
+func Load(ctx context.Context, path string) (*Plugin, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("plugins: failed to read plugin: %w", err)
+	}
+
+	// closing the module on context deadline stops plugins stuck in a loop
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	plugin, err := instantiate(ctx, runtime, code)
+	if err != nil {
+		_ = runtime.Close(ctx)
+		return nil, err
+	}
+	return plugin, nil
+}
+
+// region Public
+
+// Selects reports whether the plugin exports the select function.
+func (p *Plugin) Selects() bool {
+	return p.selectF != nil
+}
+
+// Handles reports whether the plugin exports the handle function.
+func (p *Plugin) Handles() bool {
+	return p.handleF != nil
+}
+
+// IsSelected implements selectors.Selector.
+func (p *Plugin) IsSelected(msg amqp091.Delivery) (bool, error) {
+	if p.selectF == nil {
+		return false, errors.New("plugins: plugin does not export select function")
+	}
+	// plugin instance is not safe for concurrent use
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	results, err := p.call(p.selectF, msg)
+	if err != nil {
+		return false, err
+	}
+	switch result := int32(results[0]); {
+	case result < 0:
+		return false, fmt.Errorf("plugins: select failed with code %v", result)
+	default:
+		return result > 0, nil
+	}
+}
+
+// Decide returns the verdict of the plugin about the message, see handlers.NewVerdictHandler.
+func (p *Plugin) Decide(msg amqp091.Delivery) (handlers.Verdict, error) {
+	if p.handleF == nil {
+		return handlers.Verdict{}, errors.New("plugins: plugin does not export handle function")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	results, err := p.call(p.handleF, msg)
+	if err != nil {
+		return handlers.Verdict{}, err
+	}
+
+	ptr, size := uint32(results[0]>>32), uint32(results[0])
+	data, ok := p.module.Memory().Read(ptr, size)
+	if !ok {
+		return handlers.Verdict{}, fmt.Errorf("plugins: verdict is out of plugin memory (ptr %v, size %v)", ptr, size)
+	}
+	var verdict handlers.Verdict
+	err = json.Unmarshal(data, &verdict)
+	p.release(ptr, size)
+	if err != nil {
+		return handlers.Verdict{}, fmt.Errorf("plugins: invalid verdict: %w", err)
+	}
+	return verdict, nil
+}
+
+func (p *Plugin) Close(ctx context.Context) error {
+	return p.runtime.Close(ctx)
+}
+
+// endregion
+
+// region Private
+
+func instantiate(ctx context.Context, runtime wazero.Runtime, code []byte) (*Plugin, error) {
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		return nil, fmt.Errorf("plugins: failed to instantiate WASI: %w", err)
+	}
+	compiled, err := runtime.CompileModule(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("plugins: failed to compile plugin: %w", err)
+	}
+	config := wazero.NewModuleConfig().
+		WithStdout(os.Stderr).
+		WithStderr(os.Stderr).
+		WithStartFunctions("_initialize")
+	module, err := runtime.InstantiateModule(ctx, compiled, config)
+	if err != nil {
+		return nil, fmt.Errorf("plugins: failed to instantiate plugin: %w", err)
+	}
+
+	p := &Plugin{
+		runtime: runtime,
+		module:  module,
+		alloc:   module.ExportedFunction(exportAlloc),
+		free:    module.ExportedFunction(exportFree),
+		selectF: module.ExportedFunction(exportSelect),
+		handleF: module.ExportedFunction(exportHandle),
+	}
+	if module.Memory() == nil || p.alloc == nil {
+		return nil, errors.New("plugins: plugin must export memory and alloc function")
+	}
+	if p.selectF == nil && p.handleF == nil {
+		return nil, errors.New("plugins: plugin must export select and/or handle function")
+	}
+	return p, nil
+}
+
+// call writes the message to the plugin memory and calls the function with its location.
+func (p *Plugin) call(function api.Function, msg amqp091.Delivery) ([]uint64, error) {
+	doc, err := mappers.PublishingDocument(mappers.DeliveryPublishing(msg))
+	if err != nil {
+		return nil, err
+	}
+	var line bytes.Buffer
+	if err = json.Compact(&line, doc); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+
+	size := uint32(line.Len())
+	results, err := p.alloc.Call(ctx, uint64(size))
+	if err != nil {
+		return nil, fmt.Errorf("plugins: alloc failed: %w", err)
+	}
+	ptr := uint32(results[0])
+	if !p.module.Memory().Write(ptr, line.Bytes()) {
+		return nil, fmt.Errorf("plugins: allocated buffer is out of plugin memory (ptr %v, size %v)", ptr, size)
+	}
+	defer p.release(ptr, size)
+
+	results, err = function.Call(ctx, uint64(ptr), uint64(size))
+	if err != nil {
+		return nil, fmt.Errorf("plugins: %v failed: %w", function.Definition().Name(), err)
+	}
+	if len(results) != 1 {
+		return nil, fmt.Errorf("plugins: %v must return a single value", function.Definition().Name())
+	}
+	return results, nil
+}
+
+// release frees the plugin buffer if the plugin exports the free function.
+func (p *Plugin) release(ptr, size uint32) {
+	if p.free == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	_, _ = p.free.Call(ctx, uint64(ptr), uint64(size))
+}
+
+// endregion
@@ -0,0 +1,89 @@
+package plugins_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/management/handlers"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/plugins"
+)
+
+func TestPlugins(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Plugins")
+}
+
+// pluginPath is the example plugin built from source, so that no binary is kept in the repository. Empty if it cannot be built.
+var pluginPath string
+var buildOutput string
+
+var _ = BeforeSuite(func() {
+	path := filepath.Join(GinkgoT().TempDir(), "plugin.wasm")
+	build := exec.Command("go", "build", "-buildmode=c-shared", "-o", path, ".")
+	build.Dir = filepath.Join("testdata", "plugin")
+	build.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	output, err := build.CombinedOutput()
+	if err == nil {
+		pluginPath = path
+	}
+	buildOutput = string(output)
+})
+
+var _ = Describe("WASM plugin", func() {
+	var plugin *plugins.Plugin
+
+	BeforeEach(func() {
+		if pluginPath == "" {
+			Skip("example plugin cannot be built with this Go toolchain: " + buildOutput)
+		}
+		var err error
+		plugin, err = plugins.Load(context.Background(), pluginPath)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(plugin.Close, context.Background())
+	})
+
+	It("selects messages", func() {
+		Expect(plugin.Selects()).To(BeTrue())
+
+		selected, err := plugin.IsSelected(amqp091.Delivery{Type: "selected"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(selected).To(BeTrue())
+
+		selected, err = plugin.IsSelected(amqp091.Delivery{Type: "other"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(selected).To(BeFalse())
+	})
+
+	It("handles messages by its verdicts", func() {
+		Expect(plugin.Handles()).To(BeTrue())
+		handler := handlers.NewVerdictHandler(nil, plugin.Decide)
+
+		requeue, edited, err := handler.HandleEdited(amqp091.Delivery{MessageId: "msg-1", Body: []byte("body")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeTrue())
+		Expect(edited.MessageId).To(Equal("msg-1"))
+		Expect(edited.Body).To(Equal([]byte("BODY")))
+
+		requeue, err = handler.Handle(amqp091.Delivery{Headers: amqp091.Table{"action": "drop"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeue).To(BeFalse())
+	})
+})
+
+var _ = Describe("WASM module", func() {
+	It("rejects modules which do not implement the ABI", func() {
+		// empty module: magic number and version only
+		path := filepath.Join(GinkgoT().TempDir(), "empty.wasm")
+		Expect(os.WriteFile(path, []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}, 0o600)).To(Succeed())
+
+		_, err := plugins.Load(context.Background(), path)
+		Expect(err).To(MatchError("plugins: plugin must export memory and alloc function"))
+	})
+})
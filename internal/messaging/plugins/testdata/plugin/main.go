@@ -0,0 +1,81 @@
+// Package main is an example plugin built with Go:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o plugin.wasm .
+//
+// It selects messages of type "selected". Messages with header action "drop" are dropped,
+// others are replaced by the same message with the body in upper case.
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"unsafe"
+)
+
+// buffers keeps the buffers shared with the host reachable until they are freed.
+var buffers = make(map[uint32][]byte)
+
+type document struct {
+	Headers    map[string]any `json:"headers,omitempty"`
+	MessageID  string         `json:"messageID,omitempty"`
+	Type       string         `json:"type,omitempty"`
+	BodyFormat string         `json:"bodyFormat"`
+	Body       any            `json:"body,omitempty"`
+}
+
+type verdict struct {
+	Verdict string    `json:"verdict"`
+	Message *document `json:"message,omitempty"`
+}
+
+//go:wasmexport alloc
+func alloc(size uint32) uint32 {
+	buffer := make([]byte, size+1)
+	ptr := uint32(uintptr(unsafe.Pointer(unsafe.SliceData(buffer))))
+	buffers[ptr] = buffer
+	return ptr
+}
+
+//go:wasmexport free
+func free(ptr, _ uint32) {
+	delete(buffers, ptr)
+}
+
+//go:wasmexport select
+func selectMessage(ptr, size uint32) int32 {
+	doc, ok := read(ptr, size)
+	if !ok {
+		return -1
+	}
+	if doc.Type == "selected" {
+		return 1
+	}
+	return 0
+}
+
+//go:wasmexport handle
+func handle(ptr, size uint32) uint64 {
+	doc, ok := read(ptr, size)
+	if !ok {
+		return 0
+	}
+	result := verdict{Verdict: "drop"}
+	if doc.Headers["action"] != "drop" {
+		if body, ok := doc.Body.(string); ok {
+			doc.Body = strings.ToUpper(body)
+		}
+		result = verdict{Verdict: "replace", Message: &doc}
+	}
+	data, _ := json.Marshal(result)
+	out := alloc(uint32(len(data)))
+	copy(buffers[out], data)
+	return uint64(out)<<32 | uint64(len(data))
+}
+
+func read(ptr, size uint32) (document, bool) {
+	var doc document
+	err := json.Unmarshal(buffers[ptr][:size], &doc)
+	return doc, err == nil
+}
+
+func main() {}
@@ -1,15 +1,58 @@
 package messaging
 
-import "github.com/rabbitmq/amqp091-go"
+import (
+	"context"
+	"errors"
 
-//go:generate mockery --case underscore --name "Publisher|Consumer" --output ./mocks
+	"github.com/rabbitmq/amqp091-go"
+)
+
+//go:generate mockery --case underscore --name "Publisher|PipelinedPublisher|Confirmation|Consumer|Prefetcher|MessageCounter|Reconnector" --output ./mocks
+
+// ErrConnectionLost is wrapped by errors caused by a lost connection to the broker. Such errors can be recovered from with Reconnector.
+var ErrConnectionLost = errors.New("connection lost")
 
 type Publisher interface {
 	Publish(topic string, msg amqp091.Publishing) error
 	Close() error
 }
 
+// PipelinedPublisher publishes messages without waiting for each publisher confirmation.
+// Publish only waits when the window of unconfirmed messages is full.
+type PipelinedPublisher interface {
+	Publisher
+	// Confirmation returns the confirmation of all messages published since the previous call.
+	Confirmation() Confirmation
+}
+
+// Confirmation is a pending confirmation of one or more published messages.
+type Confirmation interface {
+	// Done is closed once all messages are confirmed or confirming failed.
+	Done() <-chan struct{}
+	// Err returns the reason why confirming failed, nil if all messages were positively confirmed.
+	Err() error
+}
+
 type Consumer interface {
 	Consume(queue string) (<-chan amqp091.Delivery, error)
+	// Err returns the reason why the delivery channel returned by Consume was closed.
+	Err() error
 	Close() error
 }
+
+// Prefetcher limits the number of messages delivered to a consumer before they are acknowledged.
+type Prefetcher interface {
+	// Prefetch sets the limit of unacknowledged deliveries, 0 means no limit.
+	Prefetch(count int) error
+}
+
+// MessageCounter reads the number of messages in a queue.
+type MessageCounter interface {
+	MessageCount(queue string) (int, error)
+}
+
+// Reconnector re-establishes a lost connection to the broker.
+// Unacknowledged deliveries of the lost connection are redelivered by the broker.
+type Reconnector interface {
+	Reconnect(ctx context.Context) error
+}
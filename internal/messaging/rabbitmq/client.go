@@ -2,6 +2,7 @@ package rabbitmq
 
 import (
 	"fmt"
+	"regexp"
 
 	rabbithole "github.com/michaelklishin/rabbit-hole/v2"
 )
@@ -33,4 +34,130 @@ func (c *Client) GetQueueInfo(queue string) (rabbithole.QueueInfo, error) {
 	return rabbithole.QueueInfo{}, fmt.Errorf("rabbitmq_client: queue %v not found", queue)
 }
 
+// MessageCount returns the number of messages in the queue, as reported by the HTTP API.
+func (c *Client) MessageCount(queue string) (int, error) {
+	qInfo, err := c.GetQueueInfo(queue)
+	if err != nil {
+		return 0, err
+	}
+	return qInfo.Messages, nil
+}
+
+// GetQueueActivity returns the number of consumers of the queue and the rate at which messages are published to it.
+func (c *Client) GetQueueActivity(queue string) (QueueActivity, error) {
+	qInfo, err := c.GetQueueInfo(queue)
+	if err != nil {
+		return QueueActivity{}, err
+	}
+	activity := QueueActivity{Consumers: qInfo.Consumers}
+	if qInfo.MessageStats != nil {
+		activity.PublishRate = float64(qInfo.MessageStats.PublishDetails.Rate)
+	}
+	return activity, nil
+}
+
+// ListQueueBindings returns the bindings of the queue, except the implicit binding to the default exchange.
+func (c *Client) ListQueueBindings(vhost, queue string) ([]rabbithole.BindingInfo, error) {
+	bindings, err := c.client.ListQueueBindings(vhost, queue)
+	if err != nil {
+		return nil, fmt.Errorf("rabbitmq_client: %w", err)
+	}
+	var result []rabbithole.BindingInfo
+	for _, binding := range bindings {
+		if binding.Source != "" {
+			result = append(result, binding)
+		}
+	}
+	return result, nil
+}
+
+// DeclareBinding binds the queue to the exchange.
+func (c *Client) DeclareBinding(vhost, exchange, queue, routingKey string, args map[string]interface{}) error {
+	_, err := c.client.DeclareBinding(vhost, rabbithole.BindingInfo{
+		Source:          exchange,
+		Destination:     queue,
+		DestinationType: "queue",
+		RoutingKey:      routingKey,
+		Arguments:       args,
+	})
+	if err != nil {
+		return fmt.Errorf("rabbitmq_client: %w", err)
+	}
+	return nil
+}
+
+// DeleteBinding deletes the binding returned by ListQueueBindings.
+func (c *Client) DeleteBinding(vhost string, binding rabbithole.BindingInfo) error {
+	_, err := c.client.DeleteBinding(vhost, binding)
+	if err != nil {
+		return fmt.Errorf("rabbitmq_client: %w", err)
+	}
+	return nil
+}
+
+// DeleteQueueIfEmpty deletes the queue if it has no messages.
+func (c *Client) DeleteQueueIfEmpty(vhost, queue string) error {
+	_, err := c.client.DeleteQueue(vhost, queue, rabbithole.QueueDeleteOptions{IfEmpty: true})
+	if err != nil {
+		return fmt.Errorf("rabbitmq_client: %w", err)
+	}
+	return nil
+}
+
+// ListMatchingPolicies returns policies and operator policies which apply to the queue of the provided type.
+func (c *Client) ListMatchingPolicies(vhost, queue, queueType string) ([]MatchingPolicy, error) {
+	policies, err := c.client.ListPoliciesIn(vhost)
+	if err != nil {
+		return nil, fmt.Errorf("rabbitmq_client: %w", err)
+	}
+	operatorPolicies, err := c.client.ListOperatorPoliciesIn(vhost)
+	if err != nil {
+		return nil, fmt.Errorf("rabbitmq_client: %w", err)
+	}
+
+	var result []MatchingPolicy
+	matches := func(pattern, applyTo string) bool {
+		switch applyTo {
+		case "all", "queues", queueType + "_queues":
+		default:
+			return false
+		}
+		matched, err := regexp.MatchString(pattern, queue)
+		return err == nil && matched
+	}
+	for _, policy := range policies {
+		if matches(policy.Pattern, policy.ApplyTo) {
+			result = append(result, MatchingPolicy{Name: policy.Name, Definition: policy.Definition})
+		}
+	}
+	for _, policy := range operatorPolicies {
+		if matches(policy.Pattern, policy.ApplyTo) {
+			result = append(result, MatchingPolicy{Name: policy.Name, Operator: true, Definition: policy.Definition})
+		}
+	}
+	return result, nil
+}
+
+// endregion
+
+// region Structs
+
+// MatchingPolicy is a policy or an operator policy which applies to a queue.
+type MatchingPolicy struct {
+	Name       string
+	Operator   bool
+	Definition map[string]interface{}
+}
+
+// QueueActivity describes other clients using the queue.
+type QueueActivity struct {
+	Consumers   int
+	PublishRate float64
+}
+
+// Active reports whether the queue has consumers or messages are being published to it.
+func (a QueueActivity) Active() bool {
+	return a.Consumers > 0 || a.PublishRate > 0
+}
+
 // endregion
@@ -1,55 +1,74 @@
 package rabbitmq
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/rabbitmq/amqp091-go"
 )
 
 type Consumer struct {
+	endpoint   string
+	prefetch   int
+	exclusive  bool
 	connection *amqp091.Connection
 	channel    *amqp091.Channel
+	watcher    *channelWatcher
 	args       map[string]interface{}
 }
 
 // NewSimpleConsumer creates a new simple consumer.
 func NewSimpleConsumer(endpoint string, offset ...string) (*Consumer, error) {
-	conn, err := amqp091.Dial(endpoint)
-	if err != nil {
-		return nil, fmt.Errorf("consumer: failed to connect to RabbitMQ: %w", err)
+	var args map[string]interface{}
+	if len(offset) > 0 {
+		args = map[string]interface{}{"x-stream-offset": offset[0]}
 	}
 
-	ch, err := conn.Channel()
-	if err != nil {
-		return nil, fmt.Errorf("consumer: failed to open a channel: %w", err)
+	consumer := &Consumer{endpoint: endpoint, prefetch: 1, args: args}
+	if err := consumer.connect(); err != nil {
+		return nil, err
 	}
-	err = ch.Qos(1, 0, false)
+	return consumer, nil
+}
+
+// Prefetch sets how many messages are delivered to the consumer before they are acknowledged.
+func (s *Consumer) Prefetch(count int) error {
+	err := s.channel.Qos(count, 0, false)
 	if err != nil {
-		return nil, fmt.Errorf("consumer: failed to set QoS: %w", err)
+		return fmt.Errorf("consumer: failed to set QoS: %w", err)
 	}
+	s.prefetch = count
+	return nil
+}
 
-	var args map[string]interface{}
-	if len(offset) > 0 {
-		args = map[string]interface{}{"x-stream-offset": offset[0]}
-	}
+// Exclusive makes the consumer consume queues exclusively, so that no other consumer can attach while consuming.
+// Consuming fails if the queue already has other consumers.
+func (s *Consumer) Exclusive(exclusive bool) {
+	s.exclusive = exclusive
+}
 
-	return &Consumer{
-		connection: conn,
-		channel:    ch,
-		args:       args,
-	}, nil
+// Reconnect closes the current connection and connects again, backing off between the attempts.
+// Unacknowledged deliveries are redelivered by the broker, consuming must be started again with Consume.
+func (s *Consumer) Reconnect(ctx context.Context) error {
+	_ = s.connection.Close()
+	if err := reconnect(ctx, s.connect); err != nil {
+		return fmt.Errorf("consumer: failed to reconnect: %w", err)
+	}
+	return nil
 }
 
 // Consume starts consuming messages from the queue.
 func (s *Consumer) Consume(queue string) (<-chan amqp091.Delivery, error) {
 	msgs, err := s.channel.Consume(
-		queue,  // queue
-		"",     // consumer
-		false,  // auto-ack
-		false,  // exclusive
-		false,  // no-local
-		false,  // no-wait
-		s.args, // args
+		queue,       // queue
+		"",          // consumer
+		false,       // auto-ack
+		s.exclusive, // exclusive
+		false,       // no-local
+		false,       // no-wait
+		s.args,      // args
 	)
 	if err != nil {
 		return nil, fmt.Errorf("consumer: failed to register a consumer: %w", err)
@@ -58,6 +77,40 @@ func (s *Consumer) Consume(queue string) (<-chan amqp091.Delivery, error) {
 	return msgs, nil
 }
 
+// Get synchronously retrieves a single message from the queue. ok is false if the queue is empty.
+// Retrieved message must be acknowledged or rejected (requeued to its original position) by the caller.
+func (s *Consumer) Get(queue string) (msg amqp091.Delivery, ok bool, err error) {
+	msg, ok, err = s.channel.Get(queue, false)
+	if err != nil {
+		return amqp091.Delivery{}, false, fmt.Errorf("consumer: failed to get a message: %w", err)
+	}
+	return msg, ok, nil
+}
+
+// MessageCount returns the number of messages ready to be delivered from the queue.
+func (s *Consumer) MessageCount(queue string) (int, error) {
+	// failed passive declaration closes the channel, so a separate channel is used
+	ch, err := s.connection.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("consumer: failed to open a channel: %w", err)
+	}
+	defer func() { _ = ch.Close() }()
+
+	q, err := ch.QueueDeclarePassive(queue, true, false, false, false, nil)
+	if err != nil {
+		return 0, fmt.Errorf("consumer: failed to inspect queue: %w", err)
+	}
+	return q.Messages, nil
+}
+
+// Err returns the reason why the delivery channel returned by Consume was closed.
+func (s *Consumer) Err() error {
+	if err := s.watcher.Err(time.Second); err != nil {
+		return fmt.Errorf("consumer: %w", err)
+	}
+	return errors.New("consumer: delivery channel closed")
+}
+
 // Close closes the consumer Connection and channel.
 func (s *Consumer) Close() error {
 	if err := s.channel.Close(); err != nil {
@@ -68,3 +121,30 @@ func (s *Consumer) Close() error {
 	}
 	return nil
 }
+
+// region Helpers
+
+func (s *Consumer) connect() error {
+	conn, err := amqp091.Dial(s.endpoint)
+	if err != nil {
+		return fmt.Errorf("consumer: failed to connect to RabbitMQ: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("consumer: failed to open a channel: %w", err)
+	}
+	err = ch.Qos(s.prefetch, 0, false)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("consumer: failed to set QoS: %w", err)
+	}
+
+	s.connection = conn
+	s.channel = ch
+	s.watcher = watchChannel(conn, ch)
+	return nil
+}
+
+// endregion
@@ -2,38 +2,37 @@ package rabbitmq
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+)
+
+const confirmTimeout = time.Second * 10
+
+var (
+	errNegativeConfirmation = errors.New("publisher: negative confirmation received")
+	errConfirmationTimeout  = errors.New("publisher: waiting for publisher confirmation timed out")
 )
 
 type Publisher struct {
-	conn    *amqp091.Connection
-	channel *amqp091.Channel
+	endpoint string
+	conn     *amqp091.Connection
+	channel  *amqp091.Channel
+	watcher  *channelWatcher
 }
 
 // NewSimplePublisher creates a new simple publisher.
 func NewSimplePublisher(endpoint string) (*Publisher, error) {
-	conn, err := amqp091.Dial(endpoint)
-	if err != nil {
-		return nil, fmt.Errorf("publisher: failed to connect to RabbitMQ: %w", err)
+	publisher := &Publisher{endpoint: endpoint}
+	if err := publisher.connect(); err != nil {
+		return nil, err
 	}
-
-	ch, err := conn.Channel()
-	if err != nil {
-		return nil, fmt.Errorf("publisher: failed to open a channel: %w", err)
-	}
-
-	// put channel into confirm mode so that the client can ensure that all publishing have successfully been received by the server
-	if err = ch.Confirm(false); err != nil {
-		return nil, fmt.Errorf("publisher: channel could not be put into confirm mode: %w", err)
-	}
-
-	return &Publisher{
-		conn:    conn,
-		channel: ch,
-	}, nil
+	return publisher, nil
 }
 
 // Publish sends a message to the RabbitMQ exchange.
@@ -46,6 +45,9 @@ func (p *Publisher) Publish(topic string, msg amqp091.Publishing) error {
 		false,
 		msg,
 	)
+	if errors.Is(err, amqp091.ErrClosed) {
+		return p.closedErr()
+	}
 	if err != nil {
 		return fmt.Errorf("publisher: failed to publish a message: %w", err)
 	}
@@ -54,15 +56,27 @@ func (p *Publisher) Publish(topic string, msg amqp091.Publishing) error {
 	case <-confirm.Done():
 		pSuccess := confirm.Acked()
 		if !pSuccess {
-			return fmt.Errorf("publisher: negative confirmation received")
+			return p.confirmationErr(errNegativeConfirmation)
 		}
-	case <-time.After(time.Second * 10):
-		return fmt.Errorf("publisher: waiting for publisher confirmation timed out")
+	case <-p.watcher.Closed():
+		return p.closedErr()
+	case <-time.After(confirmTimeout):
+		return errConfirmationTimeout
 	}
 
 	return nil
 }
 
+// Reconnect closes the current connection and connects again, backing off between the attempts.
+// Messages which were not confirmed before the connection was lost may have been published or not.
+func (p *Publisher) Reconnect(ctx context.Context) error {
+	_ = p.conn.Close()
+	if err := reconnect(ctx, p.connect); err != nil {
+		return fmt.Errorf("publisher: failed to reconnect: %w", err)
+	}
+	return nil
+}
+
 // Close closes the publisher Connection and channel.
 func (p *Publisher) Close() error {
 	if err := p.channel.Close(); err != nil {
@@ -74,6 +88,164 @@ func (p *Publisher) Close() error {
 	return nil
 }
 
+// PipelinedPublisher keeps up to window unconfirmed messages in flight and tracks their confirmations asynchronously.
+type PipelinedPublisher struct {
+	*Publisher
+	window    int
+	mu        sync.Mutex
+	inFlight  []*amqp091.DeferredConfirmation
+	unclaimed []*amqp091.DeferredConfirmation
+}
+
+// NewPipelinedPublisher creates a new publisher with the window of unconfirmed messages.
+func NewPipelinedPublisher(endpoint string, window int) (*PipelinedPublisher, error) {
+	publisher, err := NewSimplePublisher(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &PipelinedPublisher{Publisher: publisher, window: max(window, 1)}, nil
+}
+
+// Publish sends a message to the RabbitMQ exchange. It only waits for a confirmation when the window is full.
+func (p *PipelinedPublisher) Publish(topic string, msg amqp091.Publishing) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// wait until there is room in the window
+	for len(p.inFlight) >= p.window {
+		select {
+		case <-p.inFlight[0].Done():
+			p.inFlight = p.inFlight[1:]
+		case <-p.watcher.Closed():
+			return p.closedErr()
+		case <-time.After(confirmTimeout):
+			return errConfirmationTimeout
+		}
+	}
+
+	confirm, err := p.channel.PublishWithDeferredConfirmWithContext(
+		context.Background(),
+		amqp091.DefaultExchange,
+		topic,
+		false,
+		false,
+		msg,
+	)
+	if errors.Is(err, amqp091.ErrClosed) {
+		return p.closedErr()
+	}
+	if err != nil {
+		return fmt.Errorf("publisher: failed to publish a message: %w", err)
+	}
+	p.inFlight = append(p.inFlight, confirm)
+	p.unclaimed = append(p.unclaimed, confirm)
+	return nil
+}
+
+// Reconnect drops the confirmations of the lost connection and connects again.
+func (p *PipelinedPublisher) Reconnect(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.inFlight = nil
+	p.unclaimed = nil
+	return p.Publisher.Reconnect(ctx)
+}
+
+// Confirmation returns the confirmation of all messages published since the previous call.
+func (p *PipelinedPublisher) Confirmation() messaging.Confirmation {
+	p.mu.Lock()
+	confirms := p.unclaimed
+	p.unclaimed = nil
+	p.mu.Unlock()
+	return newConfirmation(confirms, p.watcher)
+}
+
 // region Helpers
 
+func (p *Publisher) connect() error {
+	conn, err := amqp091.Dial(p.endpoint)
+	if err != nil {
+		return fmt.Errorf("publisher: failed to connect to RabbitMQ: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("publisher: failed to open a channel: %w", err)
+	}
+
+	// put channel into confirm mode so that the client can ensure that all publishing have successfully been received by the server
+	if err = ch.Confirm(false); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("publisher: channel could not be put into confirm mode: %w", err)
+	}
+
+	p.conn = conn
+	p.channel = ch
+	p.watcher = watchChannel(conn, ch)
+	return nil
+}
+
+// confirmationErr returns the reason of the channel closure instead of the provided error if the channel was closed, since pending confirmations are negatively confirmed on closure.
+func (p *Publisher) confirmationErr(err error) error {
+	return confirmationErr(p.watcher, err)
+}
+
+func (p *Publisher) closedErr() error {
+	return closedErr(p.watcher)
+}
+
+func confirmationErr(watcher *channelWatcher, err error) error {
+	select {
+	case <-watcher.Closed():
+		return closedErr(watcher)
+	default:
+		return err
+	}
+}
+
+func closedErr(watcher *channelWatcher) error {
+	return fmt.Errorf("publisher: %w", watcher.Err(time.Second))
+}
+
+type confirmation struct {
+	done chan struct{}
+	err  error
+}
+
+// newConfirmation waits for the confirmations in the background. The watcher is captured, since the publisher may reconnect in the meantime.
+func newConfirmation(confirms []*amqp091.DeferredConfirmation, watcher *channelWatcher) *confirmation {
+	c := &confirmation{done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		timeout := time.After(confirmTimeout)
+		for _, confirm := range confirms {
+			select {
+			case <-confirm.Done():
+				if !confirm.Acked() {
+					c.err = confirmationErr(watcher, errNegativeConfirmation)
+					return
+				}
+			case <-watcher.Closed():
+				c.err = closedErr(watcher)
+				return
+			case <-timeout:
+				c.err = errConfirmationTimeout
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func (c *confirmation) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *confirmation) Err() error {
+	<-c.done
+	return c.err
+}
+
 // endregion
@@ -0,0 +1,33 @@
+package rabbitmq
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	reconnectAttempts   = 10
+	reconnectMinBackoff = time.Millisecond * 500
+	reconnectMaxBackoff = time.Second * 30
+)
+
+// reconnect calls connect until it succeeds, backing off exponentially between the attempts.
+func reconnect(ctx context.Context, connect func() error) error {
+	backoff := reconnectMinBackoff
+	var err error
+	for attempt := 1; attempt <= reconnectAttempts; attempt++ {
+		if err = connect(); err == nil {
+			return nil
+		}
+		if attempt == reconnectAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, reconnectMaxBackoff)
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+)
+
+// channelWatcher records why the channel was closed or why its consumer was cancelled by the server.
+// Closures caused by the lost connection wrap messaging.ErrConnectionLost.
+type channelWatcher struct {
+	closed    chan struct{}
+	cancelled chan struct{}
+	cancel    sync.Once
+	mu        sync.Mutex
+	reason    error
+}
+
+func watchChannel(conn *amqp091.Connection, ch *amqp091.Channel) *channelWatcher {
+	w := &channelWatcher{closed: make(chan struct{}), cancelled: make(chan struct{})}
+	closes := ch.NotifyClose(make(chan *amqp091.Error, 1))
+	cancels := ch.NotifyCancel(make(chan string, 1))
+
+	go func() {
+		defer close(w.closed)
+		for {
+			select {
+			case tag, ok := <-cancels:
+				if !ok {
+					cancels = nil
+					continue
+				}
+				w.setReason(fmt.Errorf("consumer %v was cancelled by the server (e.g. the queue was deleted)", tag))
+				w.cancel.Do(func() { close(w.cancelled) })
+			case err, ok := <-closes:
+				switch {
+				case ok && err != nil && conn.IsClosed():
+					// connection is marked as closed before its channels are notified
+					w.setReason(fmt.Errorf("%w: %w", messaging.ErrConnectionLost, err))
+				case ok && err != nil:
+					w.setReason(fmt.Errorf("channel closed: %w", err))
+				default:
+					w.setReason(errors.New("channel closed"))
+				}
+				return
+			}
+		}
+	}()
+	return w
+}
+
+// Closed returns a channel which is closed once the watched channel is closed.
+func (w *channelWatcher) Closed() <-chan struct{} {
+	return w.closed
+}
+
+// Err returns the reason why the channel was closed or the consumer was cancelled.
+// Notifications are delivered asynchronously, so it waits for the reason up to the provided timeout.
+func (w *channelWatcher) Err(timeout time.Duration) error {
+	select {
+	case <-w.closed:
+	case <-w.cancelled:
+	case <-time.After(timeout):
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.reason
+}
+
+func (w *channelWatcher) setReason(reason error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.reason == nil {
+		w.reason = reason
+	}
+}
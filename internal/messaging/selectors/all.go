@@ -0,0 +1,22 @@
+package selectors
+
+import "github.com/rabbitmq/amqp091-go"
+
+// AllSelector selects messages selected by all of its selectors. Selectors are evaluated in order until one does not select the message.
+type AllSelector struct {
+	selectors []Selector
+}
+
+func NewAllSelector(selectors ...Selector) *AllSelector {
+	return &AllSelector{selectors: selectors}
+}
+
+func (s *AllSelector) IsSelected(msg amqp091.Delivery) (bool, error) {
+	for _, selector := range s.selectors {
+		isSelected, err := selector.IsSelected(msg)
+		if err != nil || !isSelected {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,33 @@
+package selectors_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors/mocks"
+)
+
+var _ = Describe("AllSelector", func() {
+
+	It("selects message selected by all selectors", func() {
+		selector := selectors.NewAllSelector(selectors.NewYesSelector(), selectors.NewYesSelector())
+		Expect(selector.IsSelected(amqp091.Delivery{})).To(BeTrue())
+	})
+
+	It("does not evaluate selectors after the first one which does not select message", func() {
+		last := mocks.NewSelector(GinkgoT())
+		selector := selectors.NewAllSelector(selectors.NewYesSelector(), selectors.NewNoSelector(), last)
+		Expect(selector.IsSelected(amqp091.Delivery{})).To(BeFalse())
+	})
+
+	It("returns error of selector", func() {
+		failing := mocks.NewSelector(GinkgoT())
+		failing.On("IsSelected", amqp091.Delivery{}).Return(false, errors.New("failed"))
+		_, err := selectors.NewAllSelector(failing).IsSelected(amqp091.Delivery{})
+		Expect(err).To(MatchError("failed"))
+	})
+})
@@ -0,0 +1,39 @@
+package selectors
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// KeyExpr evaluates an expression to a string key of the message, e.g. its destination queue.
+// Results of other types than string are formatted, nil results are errors.
+type KeyExpr struct {
+	program *vm.Program
+}
+
+func NewKeyExpr(keyExpr string) (*KeyExpr, error) {
+	program, err := expr.Compile(keyExpr, expr.Env(DeliverySubset{}))
+	if err != nil {
+		return nil, err
+	}
+	return &KeyExpr{program: program}, nil
+}
+
+func (e *KeyExpr) Key(msg amqp091.Delivery) (string, error) {
+	output, err := expr.Run(e.program, SubsetFromDelivery(msg))
+	if err != nil {
+		return "", err
+	}
+	switch key := output.(type) {
+	case nil:
+		return "", errors.New("key expression returned nil")
+	case string:
+		return key, nil
+	default:
+		return fmt.Sprint(key), nil
+	}
+}
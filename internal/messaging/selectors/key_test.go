@@ -0,0 +1,43 @@
+package selectors_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/selectors"
+)
+
+var _ = Describe("KeyExpr", func() {
+
+	It("returns error when expression is invalid", func() {
+		_, err := selectors.NewKeyExpr(`missing.tenant`)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("evaluates expression to key", func() {
+		keyExpr, err := selectors.NewKeyExpr(`"debug." + headers.tenant`)
+		Expect(err).ToNot(HaveOccurred())
+
+		key, err := keyExpr.Key(amqp091.Delivery{Headers: amqp091.Table{"tenant": "acme"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(key).To(Equal("debug.acme"))
+	})
+
+	It("formats keys which are not strings", func() {
+		keyExpr, err := selectors.NewKeyExpr(`headers.shard`)
+		Expect(err).ToNot(HaveOccurred())
+
+		key, err := keyExpr.Key(amqp091.Delivery{Headers: amqp091.Table{"shard": int32(7)}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(key).To(Equal("7"))
+	})
+
+	It("returns error when expression returns nil", func() {
+		keyExpr, err := selectors.NewKeyExpr(`headers.tenant`)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = keyExpr.Key(amqp091.Delivery{})
+		Expect(err).To(MatchError("key expression returned nil"))
+	})
+})
@@ -0,0 +1,13 @@
+package selectors
+
+import "github.com/rabbitmq/amqp091-go"
+
+type NoSelector struct{}
+
+func NewNoSelector() *NoSelector {
+	return &NoSelector{}
+}
+
+func (s *NoSelector) IsSelected(_ amqp091.Delivery) (bool, error) {
+	return false, nil
+}
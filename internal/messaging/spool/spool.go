@@ -0,0 +1,561 @@
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+)
+
+const (
+	// FileExtension is the extension of spool files.
+	FileExtension   = ".spool"
+	cursorExtension = ".cursor"
+	// headerSize is the size of the record header: payload length and its CRC-32 checksum.
+	headerSize = 8
+	// maxRecordSize is the largest accepted record payload, well above the largest message RabbitMQ accepts.
+	maxRecordSize = 1 << 30
+	// cursorSyncInterval is the number of acknowledgments after which the cursor is synced to disk.
+	cursorSyncInterval = 1000
+)
+
+var errUnknownDeliveryTag = errors.New("spool: unknown delivery tag")
+
+func init() {
+	// header values are encoded as interface values, so their concrete types must be registered
+	gob.Register(amqp091.Table{})
+	gob.Register([]interface{}{})
+	gob.Register(amqp091.Decimal{})
+	gob.Register(time.Time{})
+}
+
+// Spool keeps messages in a local append-only file, so that it can be used instead of a temporary queue.
+// Appended messages are confirmed once they are synced to disk and are replayed in the append order, reading the file sequentially.
+// The replay position is kept in a cursor file next to the spool, so that an interrupted replay continues where it stopped.
+// Queue names passed to Publish are kept in the records, those passed to Consume and MessageCount are ignored.
+type Spool struct {
+	path string
+
+	mu      sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	size    int64
+	synced  int64
+	records int
+	syncErr error
+
+	cursorFile *os.File
+	cursor     position
+	unsynced   int
+
+	// delivered is the number of records delivered so far, those after the cursor are redelivered on the next Consume
+	delivered int
+	nextTag   uint64
+	inFlight  map[uint64]position
+	stop      chan struct{}
+	readErr   error
+}
+
+// Open opens the spool file at path, creating it if it does not exist.
+// A partially written record at the end of the file, left behind by a crash, is discarded.
+// A corrupt record elsewhere in the file is reported as an error and the file is left unchanged.
+func Open(path string) (*Spool, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("spool: failed to open spool file: %w", err)
+	}
+	size, records, err := scan(file)
+	if err == nil {
+		err = file.Truncate(size)
+	}
+	if err == nil {
+		_, err = file.Seek(size, io.SeekStart)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("spool: failed to read spool file: %w", err)
+	}
+
+	cursorFile, err := os.OpenFile(path+cursorExtension, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("spool: failed to open cursor file: %w", err)
+	}
+	cursor, err := readCursor(cursorFile)
+	if err == nil && (cursor.Offset > size || cursor.Records > records) {
+		err = errors.New("cursor is beyond the end of the spool")
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = cursorFile.Close()
+		return nil, fmt.Errorf("spool: failed to read cursor file: %w", err)
+	}
+
+	return &Spool{
+		path:       path,
+		file:       file,
+		writer:     bufio.NewWriter(file),
+		size:       size,
+		synced:     size,
+		records:    records,
+		cursorFile: cursorFile,
+		cursor:     cursor,
+		delivered:  cursor.Records,
+	}, nil
+}
+
+// Remove removes the spool and its cursor file. The spool must be closed.
+func Remove(path string) error {
+	for _, name := range []string{path, path + cursorExtension} {
+		if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("spool: failed to remove spool: %w", err)
+		}
+	}
+	return nil
+}
+
+// region Public
+
+func (s *Spool) Path() string {
+	return s.path
+}
+
+// Publish appends the message to the spool. It is not synced to disk until its confirmation is requested.
+func (s *Spool) Publish(topic string, msg amqp091.Publishing) error {
+	return s.Append(Record{Queue: topic, Publishing: msg})
+}
+
+// Append appends the record to the spool. It is not synced to disk until its confirmation is requested.
+func (s *Spool) Append(record Record) error {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(record); err != nil {
+		return fmt.Errorf("spool: failed to encode message: %w", err)
+	}
+	if payload.Len() > maxRecordSize {
+		return fmt.Errorf("spool: failed to append message: %w", errRecordTooLarge)
+	}
+	var header [headerSize]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(header[4:], crc32.ChecksumIEEE(payload.Bytes()))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.syncErr != nil {
+		return s.syncErr
+	}
+	if _, err := s.writer.Write(header[:]); err != nil {
+		return fmt.Errorf("spool: failed to append message: %w", err)
+	}
+	if _, err := s.writer.Write(payload.Bytes()); err != nil {
+		return fmt.Errorf("spool: failed to append message: %w", err)
+	}
+	s.size += int64(headerSize + payload.Len())
+	s.records++
+	return nil
+}
+
+// Confirmation returns the confirmation of all messages appended so far, which is done once they are synced to disk.
+// Concurrent confirmations are synced together.
+func (s *Spool) Confirmation() messaging.Confirmation {
+	s.mu.Lock()
+	offset := s.size
+	s.mu.Unlock()
+
+	c := &confirmation{done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		c.err = s.sync(offset)
+	}()
+	return c
+}
+
+// Reader returns a reader of all records in the spool, regardless of the cursor.
+func (s *Spool) Reader() (*Reader, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.flush(); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("spool: failed to open spool file: %w", err)
+	}
+	return &Reader{file: file, reader: bufio.NewReader(file)}, nil
+}
+
+// Consume replays the messages after the cursor. Acknowledging a delivery moves the cursor past it.
+// The delivery channel is not closed once all messages are replayed, as the spool does not know when the replay ends.
+func (s *Spool) Consume(_ string) (<-chan amqp091.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.flush(); err != nil {
+		return nil, err
+	}
+	reader, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("spool: failed to open spool file: %w", err)
+	}
+	if _, err = reader.Seek(s.cursor.Offset, io.SeekStart); err != nil {
+		_ = reader.Close()
+		return nil, fmt.Errorf("spool: failed to read spool file: %w", err)
+	}
+
+	s.stopConsuming()
+	s.stop = make(chan struct{})
+	s.inFlight = make(map[uint64]position)
+	s.readErr = nil
+
+	deliveries := make(chan amqp091.Delivery)
+	go s.replay(bufio.NewReader(reader), reader, s.cursor, s.stop, deliveries)
+	return deliveries, nil
+}
+
+// Err returns the reason why replaying the spool failed.
+func (s *Spool) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readErr
+}
+
+// MessageCount returns the number of messages after the cursor.
+func (s *Spool) MessageCount(_ string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records - s.cursor.Records, nil
+}
+
+// Empty reports whether all messages in the spool were replayed and acknowledged.
+func (s *Spool) Empty() bool {
+	count, _ := s.MessageCount("")
+	return count == 0
+}
+
+// Ack moves the cursor past the acknowledged delivery. Deliveries are expected to be acknowledged in order.
+func (s *Spool) Ack(tag uint64, _ bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pos, ok := s.inFlight[tag]
+	if !ok {
+		return errUnknownDeliveryTag
+	}
+	delete(s.inFlight, tag)
+	if pos.Records > s.cursor.Records {
+		s.cursor = pos
+	}
+	s.unsynced++
+	if s.unsynced < cursorSyncInterval {
+		return s.writeCursor(false)
+	}
+	return s.writeCursor(true)
+}
+
+// Nack keeps the delivery in the spool, it is replayed again on the next Consume.
+func (s *Spool) Nack(tag uint64, _ bool, _ bool) error {
+	return s.Reject(tag, false)
+}
+
+// Reject keeps the delivery in the spool, it is replayed again on the next Consume.
+func (s *Spool) Reject(tag uint64, _ bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.inFlight[tag]; !ok {
+		return errUnknownDeliveryTag
+	}
+	delete(s.inFlight, tag)
+	return nil
+}
+
+// Close stops the replay, syncs appended messages and the cursor to disk and closes the spool files.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopConsuming()
+	err := s.flush()
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err == nil {
+		err = s.writeCursor(true)
+	}
+	if closeErr := s.file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("spool: failed to close spool file: %w", closeErr)
+	}
+	if closeErr := s.cursorFile.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("spool: failed to close cursor file: %w", closeErr)
+	}
+	return err
+}
+
+// endregion
+
+// region Private
+
+// sync syncs the spool to disk up to the offset, unless it was already synced by another confirmation.
+func (s *Spool) sync(offset int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.syncErr != nil {
+		return s.syncErr
+	}
+	if s.synced >= offset {
+		return nil
+	}
+	size := s.size
+	if err := s.flush(); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		s.syncErr = fmt.Errorf("spool: failed to sync spool file: %w", err)
+		return s.syncErr
+	}
+	s.synced = size
+	return nil
+}
+
+func (s *Spool) flush() error {
+	if s.syncErr != nil {
+		return s.syncErr
+	}
+	if err := s.writer.Flush(); err != nil {
+		// the file may end with a partial record, so nothing can be appended anymore
+		s.syncErr = fmt.Errorf("spool: failed to write spool file: %w", err)
+		return s.syncErr
+	}
+	return nil
+}
+
+// replay sends the records starting at the cursor until the end of the spool or until the replay is stopped.
+func (s *Spool) replay(reader *bufio.Reader, file *os.File, pos position, stop <-chan struct{}, deliveries chan<- amqp091.Delivery) {
+	defer func() { _ = file.Close() }()
+	for {
+		record, n, err := readRecord(reader)
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			s.mu.Lock()
+			s.readErr = fmt.Errorf("spool: failed to read spool file: %w", err)
+			s.mu.Unlock()
+			return
+		}
+		pos = position{Offset: pos.Offset + n, Records: pos.Records + 1}
+
+		s.mu.Lock()
+		s.nextTag++
+		tag := s.nextTag
+		s.inFlight[tag] = pos
+		redelivered := pos.Records <= s.delivered
+		s.delivered = max(s.delivered, pos.Records)
+		s.mu.Unlock()
+
+		select {
+		case deliveries <- delivery(s, tag, redelivered, record.Publishing):
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Spool) stopConsuming() {
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// writeCursor overwrites the cursor file. Without sync, the cursor may be behind after a crash, so replayed messages can be duplicated, but never lost.
+func (s *Spool) writeCursor(sync bool) error {
+	var data [16]byte
+	binary.BigEndian.PutUint64(data[:8], uint64(s.cursor.Offset))
+	binary.BigEndian.PutUint64(data[8:], uint64(s.cursor.Records))
+	if _, err := s.cursorFile.WriteAt(data[:], 0); err != nil {
+		return fmt.Errorf("spool: failed to write cursor file: %w", err)
+	}
+	if !sync {
+		return nil
+	}
+	if err := s.cursorFile.Sync(); err != nil {
+		return fmt.Errorf("spool: failed to sync cursor file: %w", err)
+	}
+	s.unsynced = 0
+	return nil
+}
+
+// endregion
+
+// region Helpers
+
+// scan returns the offset after the last complete record and the number of complete records.
+// Only the last record may be incomplete or corrupt, as left behind by a crash while appending it.
+// A corrupt record followed by other records is reported as an error, so that they are not discarded.
+func scan(file *os.File) (int64, int, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	reader := bufio.NewReader(file)
+	var size int64
+	var records int
+	for {
+		header, err := reader.Peek(headerSize)
+		if errors.Is(err, io.EOF) {
+			return size, records, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		end := size + headerSize + int64(binary.BigEndian.Uint32(header[:4]))
+		if end > info.Size() {
+			return size, records, nil
+		}
+
+		_, n, err := readRecord(reader)
+		if errors.Is(err, errChecksumMismatch) && end == info.Size() {
+			return size, records, nil
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("record %d at offset %d: %w", records, size, err)
+		}
+		size += n
+		records++
+	}
+}
+
+var (
+	errChecksumMismatch = errors.New("record checksum mismatch")
+	errRecordTooLarge   = errors.New("record is too large")
+)
+
+// readRecord reads the next record and returns its size. io.EOF is only returned at the end of the last complete record.
+func readRecord(reader *bufio.Reader) (Record, int64, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return Record{}, 0, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length > maxRecordSize {
+		return Record{}, 0, errRecordTooLarge
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return Record{}, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return Record{}, 0, errChecksumMismatch
+	}
+
+	var record Record
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&record); err != nil {
+		return Record{}, 0, fmt.Errorf("failed to decode message: %w", err)
+	}
+	return record, int64(headerSize + len(payload)), nil
+}
+
+func readCursor(file *os.File) (position, error) {
+	var data [16]byte
+	n, err := file.ReadAt(data[:], 0)
+	if n == 0 && errors.Is(err, io.EOF) {
+		return position{}, nil
+	}
+	if err != nil {
+		return position{}, err
+	}
+	return position{Offset: int64(binary.BigEndian.Uint64(data[:8])), Records: int(binary.BigEndian.Uint64(data[8:]))}, nil
+}
+
+func delivery(acknowledger amqp091.Acknowledger, tag uint64, redelivered bool, msg amqp091.Publishing) amqp091.Delivery {
+	return amqp091.Delivery{
+		Acknowledger:    acknowledger,
+		Headers:         msg.Headers,
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		DeliveryMode:    msg.DeliveryMode,
+		Priority:        msg.Priority,
+		CorrelationId:   msg.CorrelationId,
+		ReplyTo:         msg.ReplyTo,
+		Expiration:      msg.Expiration,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		UserId:          msg.UserId,
+		AppId:           msg.AppId,
+		DeliveryTag:     tag,
+		Redelivered:     redelivered,
+		Body:            msg.Body,
+	}
+}
+
+// endregion
+
+// region Structs
+
+// Record is a message kept in the spool, together with the queue it belongs to and its position in that queue, if known.
+type Record struct {
+	Queue      string
+	Position   int
+	Publishing amqp091.Publishing
+}
+
+// Reader reads spool records sequentially.
+type Reader struct {
+	file   *os.File
+	reader *bufio.Reader
+}
+
+// Next returns the next record, io.EOF once all records are read.
+func (r *Reader) Next() (Record, error) {
+	record, _, err := readRecord(r.reader)
+	if errors.Is(err, io.EOF) {
+		return Record{}, io.EOF
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("spool: failed to read spool file: %w", err)
+	}
+	return record, nil
+}
+
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
+
+// position is the replay position: the offset after the last acknowledged record and the number of records up to it.
+type position struct {
+	Offset  int64
+	Records int
+}
+
+type confirmation struct {
+	done chan struct{}
+	err  error
+}
+
+func (c *confirmation) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *confirmation) Err() error {
+	<-c.done
+	return c.err
+}
+
+// endregion
@@ -0,0 +1,227 @@
+package spool_test
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/spool"
+)
+
+func TestSpool(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Spool")
+}
+
+var _ = Describe("Spool", func() {
+	var path string
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "operation"+spool.FileExtension)
+	})
+
+	appendMessages := func(s *spool.Spool, ids ...string) {
+		for _, id := range ids {
+			Expect(s.Publish("", amqp091.Publishing{MessageId: id, Body: []byte(id)})).To(Succeed())
+		}
+		Expect(s.Confirmation().Err()).To(Succeed())
+	}
+
+	// corrupt flips a byte at the offset, counted from the end of the file if negative.
+	corrupt := func(path string, offset int64) {
+		data, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		if offset < 0 {
+			offset += int64(len(data))
+		}
+		data[offset] ^= 0xff
+		Expect(os.WriteFile(path, data, 0o600)).To(Succeed())
+	}
+
+	It("replays appended messages in order with their properties", func() {
+		s, err := spool.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(s.Close)
+
+		timestamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		Expect(s.Publish("", amqp091.Publishing{
+			MessageId: "msg-1",
+			Priority:  3,
+			Timestamp: timestamp,
+			Headers:   amqp091.Table{"count": int64(2), "nested": amqp091.Table{"list": []interface{}{"a", int32(1)}}},
+			Body:      []byte("body"),
+		})).To(Succeed())
+		appendMessages(s, "msg-2")
+		Expect(s.MessageCount("")).To(Equal(2))
+
+		deliveries, err := s.Consume("")
+		Expect(err).ToNot(HaveOccurred())
+		var msg amqp091.Delivery
+		Eventually(deliveries).Should(Receive(&msg))
+		Expect(msg.MessageId).To(Equal("msg-1"))
+		Expect(msg.Priority).To(Equal(uint8(3)))
+		Expect(msg.Timestamp.Equal(timestamp)).To(BeTrue())
+		Expect(msg.Headers).To(Equal(amqp091.Table{"count": int64(2), "nested": amqp091.Table{"list": []interface{}{"a", int32(1)}}}))
+		Expect(msg.Body).To(Equal([]byte("body")))
+		Expect(msg.Ack(false)).To(Succeed())
+
+		Eventually(deliveries).Should(Receive(&msg))
+		Expect(msg.MessageId).To(Equal("msg-2"))
+		Expect(msg.Ack(false)).To(Succeed())
+		Consistently(deliveries, 50*time.Millisecond).ShouldNot(Receive())
+		Expect(s.Empty()).To(BeTrue())
+	})
+
+	It("continues the replay from the cursor after reopening", func() {
+		s, err := spool.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		appendMessages(s, "msg-1", "msg-2", "msg-3")
+
+		deliveries, err := s.Consume("")
+		Expect(err).ToNot(HaveOccurred())
+		var msg amqp091.Delivery
+		Eventually(deliveries).Should(Receive(&msg))
+		Expect(msg.Ack(false)).To(Succeed())
+		Eventually(deliveries).Should(Receive(&msg))
+		Expect(msg.Reject(false)).To(Succeed())
+		Expect(s.Close()).To(Succeed())
+
+		s, err = spool.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(s.Close)
+		Expect(s.MessageCount("")).To(Equal(2))
+		deliveries, err = s.Consume("")
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(deliveries).Should(Receive(&msg))
+		Expect(msg.MessageId).To(Equal("msg-2"))
+	})
+
+	It("marks messages delivered before the previous consume as redelivered", func() {
+		s, err := spool.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(s.Close)
+		appendMessages(s, "msg-1", "msg-2")
+
+		deliveries, err := s.Consume("")
+		Expect(err).ToNot(HaveOccurred())
+		var msg amqp091.Delivery
+		Eventually(deliveries).Should(Receive(&msg))
+		Expect(msg.Redelivered).To(BeFalse())
+
+		deliveries, err = s.Consume("")
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(deliveries).Should(Receive(&msg))
+		Expect(msg.MessageId).To(Equal("msg-1"))
+		Expect(msg.Redelivered).To(BeTrue())
+		Expect(msg.Ack(false)).To(Succeed())
+		Eventually(deliveries).Should(Receive(&msg))
+		Expect(msg.MessageId).To(Equal("msg-2"))
+		Expect(msg.Ack(false)).To(Succeed())
+	})
+
+	It("discards a partially written record left behind by a crash", func() {
+		s, err := spool.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		appendMessages(s, "msg-1", "msg-2")
+		Expect(s.Close()).To(Succeed())
+
+		info, err := os.Stat(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.Truncate(path, info.Size()-1)).To(Succeed())
+
+		s, err = spool.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(s.Close)
+		Expect(s.MessageCount("")).To(Equal(1))
+		appendMessages(s, "msg-3")
+
+		deliveries, err := s.Consume("")
+		Expect(err).ToNot(HaveOccurred())
+		var msg amqp091.Delivery
+		Eventually(deliveries).Should(Receive(&msg))
+		Expect(msg.MessageId).To(Equal("msg-1"))
+		Eventually(deliveries).Should(Receive(&msg))
+		Expect(msg.MessageId).To(Equal("msg-3"))
+	})
+
+	It("discards a corrupt last record left behind by a crash", func() {
+		s, err := spool.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		appendMessages(s, "msg-1", "msg-2")
+		Expect(s.Close()).To(Succeed())
+		corrupt(path, -1)
+
+		s, err = spool.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(s.Close)
+		Expect(s.MessageCount("")).To(Equal(1))
+	})
+
+	It("returns error and keeps the file if a record before the last one is corrupt", func() {
+		s, err := spool.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		appendMessages(s, "msg-1", "msg-2")
+		Expect(s.Close()).To(Succeed())
+		corrupt(path, 10)
+		info, err := os.Stat(path)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = spool.Open(path)
+		Expect(err).To(MatchError(ContainSubstring("record checksum mismatch")))
+		Expect(os.Stat(path)).To(HaveField("Size()", info.Size()))
+	})
+
+	It("returns error if a record is longer than the limit", func() {
+		data := make([]byte, 16)
+		binary.BigEndian.PutUint32(data, math.MaxUint32)
+		Expect(os.WriteFile(path, data, 0o600)).To(Succeed())
+		// the file is long enough for the record, so it is not taken for a partially written one
+		Expect(os.Truncate(path, math.MaxUint32+16)).To(Succeed())
+
+		_, err := spool.Open(path)
+		Expect(err).To(MatchError(ContainSubstring("record is too large")))
+	})
+
+	It("removes the spool and its cursor", func() {
+		s, err := spool.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Close()).To(Succeed())
+
+		Expect(spool.Remove(path)).To(Succeed())
+		entries, err := os.ReadDir(filepath.Dir(path))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("reads all records with their queue and position regardless of the cursor", func() {
+		s, err := spool.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(s.Close)
+		Expect(s.Append(spool.Record{Queue: "srcQueue", Position: 3, Publishing: amqp091.Publishing{MessageId: "msg-1"}})).To(Succeed())
+
+		deliveries, err := s.Consume("")
+		Expect(err).ToNot(HaveOccurred())
+		var msg amqp091.Delivery
+		Eventually(deliveries).Should(Receive(&msg))
+		Expect(msg.Ack(false)).To(Succeed())
+
+		reader, err := s.Reader()
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(reader.Close)
+		record, err := reader.Next()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(record.Queue).To(Equal("srcQueue"))
+		Expect(record.Position).To(Equal(3))
+		Expect(record.Publishing.MessageId).To(Equal("msg-1"))
+		_, err = reader.Next()
+		Expect(err).To(MatchError(io.EOF))
+	})
+})
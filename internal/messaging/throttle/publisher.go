@@ -0,0 +1,125 @@
+package throttle
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"golang.org/x/time/rate"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging"
+)
+
+// defaultCheckInterval is the default interval of reading the depth of destination queues.
+const defaultCheckInterval = 5 * time.Second
+
+// Publisher limits the rate of publishing and pauses publishing while the destination queue holds too many messages.
+// Waiting is skipped once the stop channel is closed, so that a graceful stop is not delayed, and fails once the context is done.
+type Publisher struct {
+	messaging.Publisher
+	log           *slog.Logger
+	ctx           context.Context
+	stop          <-chan struct{}
+	limiter       *rate.Limiter
+	depth         func(queue string) (int, error)
+	maxDepth      int
+	checkInterval time.Duration
+	checked       map[string]time.Time
+}
+
+func NewPublisher(publisher messaging.Publisher, log *slog.Logger) *Publisher {
+	return &Publisher{Publisher: publisher, log: log, ctx: context.Background(), checkInterval: defaultCheckInterval, checked: make(map[string]time.Time)}
+}
+
+// WithContext sets the context which aborts waiting once it is done.
+func (p *Publisher) WithContext(ctx context.Context) *Publisher {
+	p.ctx = ctx
+	return p
+}
+
+// WithStop sets the channel which is closed when the operation is stopped gracefully.
+func (p *Publisher) WithStop(stop <-chan struct{}) *Publisher {
+	p.stop = stop
+	return p
+}
+
+// WithRate limits publishing to rate messages per second, with bursts of up to burst messages.
+func (p *Publisher) WithRate(perSecond float64, burst int) *Publisher {
+	p.limiter = rate.NewLimiter(rate.Limit(perSecond), max(burst, 1))
+	return p
+}
+
+// WithMaxDepth pauses publishing while the depth of the destination queue, read with depth every checkInterval, is above maxDepth.
+func (p *Publisher) WithMaxDepth(depth func(queue string) (int, error), maxDepth int, checkInterval time.Duration) *Publisher {
+	p.depth = depth
+	p.maxDepth = maxDepth
+	if checkInterval > 0 {
+		p.checkInterval = checkInterval
+	}
+	return p
+}
+
+func (p *Publisher) Publish(topic string, msg amqp091.Publishing) error {
+	ctx, cancel := p.waitContext()
+	defer cancel()
+
+	if err := p.waitForDepth(ctx, topic); err != nil {
+		return err
+	}
+	if p.limiter != nil {
+		if err := p.limiter.Wait(ctx); err != nil && p.ctx.Err() != nil {
+			return p.ctx.Err()
+		}
+	}
+	return p.Publisher.Publish(topic, msg)
+}
+
+// region Private
+
+// waitContext returns the context of waiting, which is done once the publisher context is done or the operation is stopped.
+func (p *Publisher) waitContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	if p.stop == nil {
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// waitForDepth waits while the depth of the queue is above the maximal depth. The depth is read at most once per check interval.
+func (p *Publisher) waitForDepth(ctx context.Context, queue string) error {
+	if p.depth == nil || time.Since(p.checked[queue]) < p.checkInterval {
+		return nil
+	}
+	for {
+		depth, err := p.depth(queue)
+		if err != nil {
+			return err
+		}
+		p.checked[queue] = time.Now()
+		if depth <= p.maxDepth {
+			return nil
+		}
+
+		p.log.Info("destination queue depth is above the maximal depth, publishing is paused",
+			slog.String("queue", queue),
+			slog.Int("depth", depth),
+			slog.Int("maxDepth", p.maxDepth),
+		)
+		select {
+		case <-ctx.Done():
+			// stopped operation publishes the current message without waiting
+			return p.ctx.Err()
+		case <-time.After(p.checkInterval):
+		}
+	}
+}
+
+// endregion
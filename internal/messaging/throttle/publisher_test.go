@@ -0,0 +1,92 @@
+package throttle_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/mock"
+
+	mmocks "github.com/happening-oss/rabbitmq-message-ops/internal/messaging/mocks"
+	"github.com/happening-oss/rabbitmq-message-ops/internal/tests/util"
+
+	"github.com/happening-oss/rabbitmq-message-ops/internal/messaging/throttle"
+)
+
+func TestThrottle(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Throttle tests")
+}
+
+var _ = Describe("Throttled publisher", func() {
+	var pubMock *mmocks.Publisher
+	var log *slog.Logger
+
+	BeforeEach(func() {
+		pubMock = mmocks.NewPublisher(GinkgoT())
+		log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	})
+
+	It("limits the rate of publishing after the burst", func() {
+		pubMock.On(util.NameOf(pubMock.Publish), "destQueue", mock.Anything).Return(nil).Times(5)
+		publisher := throttle.NewPublisher(pubMock, log).WithRate(20, 2)
+
+		start := time.Now()
+		for i := 0; i < 2; i++ {
+			Expect(publisher.Publish("destQueue", amqp091.Publishing{})).To(Succeed())
+		}
+		Expect(time.Since(start)).To(BeNumerically("<", 25*time.Millisecond))
+
+		for i := 0; i < 3; i++ {
+			Expect(publisher.Publish("destQueue", amqp091.Publishing{})).To(Succeed())
+		}
+		Expect(time.Since(start)).To(BeNumerically(">=", 140*time.Millisecond))
+	})
+
+	It("pauses publishing while the destination queue depth is above the maximal depth", func() {
+		pubMock.On(util.NameOf(pubMock.Publish), "destQueue", mock.Anything).Return(nil).Twice()
+		depths := []int{120, 101, 100, 500}
+		depth := func(queue string) (int, error) {
+			Expect(queue).To(Equal("destQueue"))
+			d := depths[0]
+			depths = depths[1:]
+			return d, nil
+		}
+		publisher := throttle.NewPublisher(pubMock, log).WithMaxDepth(depth, 100, 20*time.Millisecond)
+
+		start := time.Now()
+		Expect(publisher.Publish("destQueue", amqp091.Publishing{})).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 40*time.Millisecond))
+		Expect(depths).To(HaveLen(1))
+
+		// depth is not read again within the check interval
+		Expect(publisher.Publish("destQueue", amqp091.Publishing{})).To(Succeed())
+		Expect(depths).To(HaveLen(1))
+	})
+
+	It("publishes without waiting once the operation is stopped", func() {
+		pubMock.On(util.NameOf(pubMock.Publish), "destQueue", mock.Anything).Return(nil).Once()
+		stop := make(chan struct{})
+		close(stop)
+		depth := func(string) (int, error) { return 1000, nil }
+		publisher := throttle.NewPublisher(pubMock, log).WithMaxDepth(depth, 100, time.Hour).WithStop(stop)
+
+		Expect(publisher.Publish("destQueue", amqp091.Publishing{})).To(Succeed())
+	})
+
+	It("returns error if the context is done while waiting", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		depth := func(string) (int, error) {
+			cancel()
+			return 1000, nil
+		}
+		publisher := throttle.NewPublisher(pubMock, log).WithMaxDepth(depth, 100, time.Hour).WithContext(ctx)
+
+		Expect(publisher.Publish("destQueue", amqp091.Publishing{})).To(MatchError(context.Canceled))
+	})
+})